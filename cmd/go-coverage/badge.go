@@ -0,0 +1,43 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+
+	coverage "github.com/rayque/go-coverage/pkg"
+)
+
+func runBadge(args []string) int {
+	fs := flag.NewFlagSet("badge", flag.ExitOnError)
+	global := addGlobalFlags(fs, "Suppress output messages")
+	outputFile := fs.String("output", "coverage.svg", "Path to the output SVG file")
+	label := fs.String("label", "coverage", "Text on the left side of the badge")
+	style := fs.String("style", "flat", "Badge style: flat or flat-square")
+	setUsage(fs, "go-coverage badge [options]",
+		"Writes an SVG badge showing the overall coverage.",
+		"go-coverage badge -output=docs/coverage.svg",
+		"go-coverage badge -label=tests -style=flat-square")
+	fs.Parse(args)
+	if *style != "flat" && *style != "flat-square" {
+		fmt.Fprintf(os.Stderr, "Error: unknown style '%s'\n\n", *style)
+		fs.Usage()
+		return 2
+	}
+	quiet := global.quiet
+	report, err := global.load()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		return 1
+	}
+	_, _, pct := report.GetOverallStats()
+	badge := &coverage.Badge{Label: *label, Coverage: pct, Style: *style}
+	if err := badge.Generate(*outputFile); err != nil {
+		fmt.Fprintf(os.Stderr, "Error generating badge: %v\n", err)
+		return 1
+	}
+	if !*quiet {
+		fmt.Printf("🏷️  Badge written to %s (%s)\n", *outputFile, coverage.FormatPercentage(pct))
+	}
+	return 0
+}
@@ -0,0 +1,61 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+
+	coverage "github.com/rayque/go-coverage/pkg"
+)
+
+const exitThresholdViolation = 3
+
+func runCheck(args []string) int {
+	fs := flag.NewFlagSet("check", flag.ExitOnError)
+	var overrides stringList
+	global := addGlobalFlags(fs, "Only print threshold violations")
+	minTotal := fs.Float64("min-total", 0, "Minimum overall coverage percentage")
+	minFile := fs.Float64("min-file", 0, "Minimum coverage percentage for every file")
+	minPackage := fs.Float64("min-package", 0, "Minimum coverage percentage for every package")
+	fs.Var(&overrides, "override", "Per-path minimum as <pattern>=<percent>, repeatable (pattern is a file or package path, glob or prefix/...)")
+	setUsage(fs, "go-coverage check [options]",
+		fmt.Sprintf("Fails with exit code %d when coverage is below the configured thresholds.", exitThresholdViolation),
+		"go-coverage check -min-total=80",
+		"go-coverage check -min-file=50 -override='example.com/app/internal/gen/...=0'")
+	fs.Parse(args)
+	thresholds := coverage.Thresholds{
+		MinTotal:   *minTotal,
+		MinFile:    *minFile,
+		MinPackage: *minPackage,
+	}
+	for _, value := range overrides {
+		override, err := coverage.ParseThresholdOverride(value)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			return 2
+		}
+		thresholds.Overrides = append(thresholds.Overrides, override)
+	}
+	quiet := global.quiet
+	report, err := global.load()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		return 1
+	}
+	if !*quiet {
+		totalStmts, coveredStmts, overallPct := report.GetOverallStats()
+		fmt.Printf("📈 Overall coverage: %.1f%% (%d/%d statements)\n", overallPct, coveredStmts, totalStmts)
+	}
+	violations := coverage.CheckThresholds(report, thresholds)
+	for _, violation := range violations {
+		fmt.Printf("❌ %s\n", violation)
+	}
+	if len(violations) > 0 {
+		fmt.Printf("%d coverage threshold(s) not met\n", len(violations))
+		return exitThresholdViolation
+	}
+	if !*quiet {
+		fmt.Printf("✅ All coverage thresholds met\n")
+	}
+	return 0
+}
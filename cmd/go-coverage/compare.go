@@ -0,0 +1,69 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+
+	coverage "github.com/rayque/go-coverage/pkg"
+)
+
+func runCompare(args []string) int {
+	fs := flag.NewFlagSet("compare", flag.ExitOnError)
+	var bases, heads stringList
+	fs.Var(&bases, "base", "Path or glob of a baseline coverage file, repeatable")
+	fs.Var(&heads, "head", "Path or glob of a coverage file to compare against the baseline, repeatable")
+	inputFormat, quiet := addFormatFlags(fs, "Suppress output messages")
+	outputFile := fs.String("output", "", "Write an HTML comparison report to this file instead of printing a table")
+	baseRev := fs.String("base-src-rev", "", "Read the sources of -base from this git revision of -src-root, so moved lines are matched when listing newly uncovered lines")
+	sources := addSourceFlags(fs)
+	setUsage(fs, "go-coverage compare -base=<file> -head=<file> [options]",
+		"Shows per-package and per-file coverage changes between two reports.",
+		"go-coverage compare -base=main.out -head=coverage.out",
+		"go-coverage compare -base=main.out -head=coverage.out -output=compare.html",
+		"go-coverage compare -base=main.out -head=coverage.out -base-src-rev=origin/main")
+	fs.Parse(args)
+	if len(bases) == 0 || len(heads) == 0 {
+		fmt.Fprintf(os.Stderr, "Error: both -base and -head are required\n\n")
+		fs.Usage()
+		return 2
+	}
+	base, err := loadReport(bases, *inputFormat, *quiet)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		return 1
+	}
+	head, err := loadReport(heads, *inputFormat, *quiet)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		return 1
+	}
+	var baseSources, headSources coverage.SourceResolver
+	if *baseRev != "" {
+		if baseSources, err = coverage.NewGitResolver(*sources.root, *baseRev); err != nil {
+			fmt.Fprintf(os.Stderr, "Error loading base source files: %v\n", err)
+			return 1
+		}
+		if headSources, err = sources.resolver(); err != nil {
+			fmt.Fprintf(os.Stderr, "Error loading source files: %v\n", err)
+			return 1
+		}
+	}
+	comparison := coverage.CompareReportsWithSources(base, head, baseSources, headSources)
+	if *outputFile == "" {
+		if err := coverage.WriteComparisonText(os.Stdout, comparison); err != nil {
+			fmt.Fprintf(os.Stderr, "Error writing comparison: %v\n", err)
+			return 1
+		}
+		return 0
+	}
+	if err := coverage.GenerateComparisonHTML(comparison, *outputFile); err != nil {
+		fmt.Fprintf(os.Stderr, "Error generating HTML report: %v\n", err)
+		return 1
+	}
+	if !*quiet {
+		fmt.Printf("📈 Coverage: %.1f%% → %.1f%% (%s)\n", comparison.BaseCoverage, comparison.HeadCoverage, coverage.FormatDelta(comparison.Delta))
+		fmt.Printf("🌐 Open %s in your browser to view the comparison\n", *outputFile)
+	}
+	return 0
+}
@@ -0,0 +1,55 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"os"
+
+	coverage "github.com/rayque/go-coverage/pkg"
+)
+
+func runConvert(args []string) int {
+	fs := flag.NewFlagSet("convert", flag.ExitOnError)
+	global := addGlobalFlags(fs, "Suppress output messages")
+	outputFormat := fs.String("output-format", "text", "Output format: text (coverage profile) or json (go-coverage JSON report)")
+	outputFile := fs.String("output", "-", "Path to the output file; - writes to stdout")
+	sources := addSourceFlags(fs)
+	setUsage(fs, "go-coverage convert -input-format=<format> -output-format=<format> [options]",
+		"Converts coverage data between the input formats go-coverage reads. The JSON report\nincludes functions and lines, so its sources are resolved with the -src-* options.",
+		"go-coverage convert -input-format=covdata -input=./covdata -output=coverage.out",
+		"go-coverage convert -input-format=json -input=coverage.json -output=coverage.out",
+		"go-coverage convert -output-format=json -output=coverage.json")
+	fs.Parse(args)
+	if *outputFormat != "text" && *outputFormat != "json" {
+		fmt.Fprintf(os.Stderr, "Error: unknown output format '%s'\n\n", *outputFormat)
+		fs.Usage()
+		return 2
+	}
+	if *outputFile == "-" {
+		*global.quiet = true
+	}
+	report, err := global.load()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		return 1
+	}
+	write := func(w io.Writer) error { return coverage.WriteCoverageProfile(w, report) }
+	if *outputFormat == "json" {
+		resolver, err := sources.resolver()
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error loading source files: %v\n", err)
+			return 1
+		}
+		jsonGen := &coverage.JSONReport{Report: report, Resolver: resolver, Version: version}
+		write = jsonGen.Render
+	}
+	if err := writeOutput(*outputFile, write); err != nil {
+		fmt.Fprintf(os.Stderr, "Error writing %s output: %v\n", *outputFormat, err)
+		return 1
+	}
+	if !*global.quiet {
+		fmt.Printf("✅ Converted %s input to %s: %s\n", *global.inputFormat, *outputFormat, *outputFile)
+	}
+	return 0
+}
@@ -0,0 +1,102 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+
+	coverage "github.com/rayque/go-coverage/pkg"
+)
+
+func runDiff(args []string) int {
+	fs := flag.NewFlagSet("diff", flag.ExitOnError)
+	global := addGlobalFlags(fs, "Only print uncovered changed lines and threshold failures")
+	base := fs.String("base", "", "Git revision to diff against (runs git diff <base>...HEAD in -src-root)")
+	diffFile := fs.String("diff-file", "", "Read a unified diff from this file instead of running git (- for stdin)")
+	minPatch := fs.Float64("min-patch", 0, "Minimum coverage percentage of changed lines")
+	outputFile := fs.String("output", "", "Also write an HTML report highlighting changed lines to this file")
+	sources := addSourceFlags(fs)
+	setUsage(fs, "go-coverage diff [options]",
+		"Reports coverage of the lines added or modified by a change.",
+		"go-coverage diff -base=origin/main -min-patch=80",
+		"git diff -U0 main | go-coverage diff -diff-file=- -output=diff.html")
+	fs.Parse(args)
+	if *base == "" && *diffFile == "" {
+		fmt.Fprintf(os.Stderr, "Error: one of -base or -diff-file is required\n\n")
+		fs.Usage()
+		return 2
+	}
+	quiet := global.quiet
+	report, err := global.load()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		return 1
+	}
+	resolver, err := sources.resolver()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error loading source files: %v\n", err)
+		return 1
+	}
+	var changes coverage.ChangedLines
+	if *diffFile != "" {
+		changes, err = readDiffFile(*diffFile)
+	} else {
+		changes, err = coverage.GitDiff(*sources.root, *base)
+	}
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error reading diff: %v\n", err)
+		return 1
+	}
+	moduleDir := ""
+	if *diffFile != "" {
+		moduleDir = coverage.GitPrefix(*sources.root)
+	}
+	changes = changes.Resolve(report, resolver, moduleDir)
+	diff := coverage.ComputeDiffCoverage(report, changes, resolver)
+	for _, file := range diff.Files {
+		total, covered, pct := file.GetCoverageStats()
+		if *quiet && len(file.Uncovered) == 0 {
+			continue
+		}
+		line := fmt.Sprintf("%s: %d/%d changed lines covered (%.1f%%)", file.Path, covered, total, pct)
+		if len(file.Uncovered) > 0 {
+			line += fmt.Sprintf(", uncovered: %s", coverage.FormatLineRanges(file.Uncovered))
+		}
+		fmt.Println(line)
+	}
+	total, covered, pct := diff.GetCoverageStats()
+	if !*quiet {
+		if total == 0 {
+			fmt.Printf("📈 Patch coverage: no executable lines changed\n")
+		} else {
+			fmt.Printf("📈 Patch coverage: %.1f%% (%d/%d changed lines)\n", pct, covered, total)
+		}
+	}
+	if *outputFile != "" {
+		htmlGen := &coverage.HTMLReport{Report: report, Resolver: resolver, Changes: changes}
+		if err := htmlGen.Generate(*outputFile); err != nil {
+			fmt.Fprintf(os.Stderr, "Error generating HTML report: %v\n", err)
+			return 1
+		}
+		if !*quiet {
+			fmt.Printf("🌐 Open %s in your browser to view the report\n", *outputFile)
+		}
+	}
+	if total > 0 && pct < *minPatch {
+		fmt.Printf("❌ patch coverage %.1f%% is below minimum %.1f%%\n", pct, *minPatch)
+		return exitThresholdViolation
+	}
+	return 0
+}
+
+func readDiffFile(name string) (coverage.ChangedLines, error) {
+	if name == "-" {
+		return coverage.ParseUnifiedDiff(os.Stdin)
+	}
+	file, err := os.Open(name)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+	return coverage.ParseUnifiedDiff(file)
+}
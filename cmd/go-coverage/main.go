@@ -3,55 +3,243 @@ package main
 import (
 	"flag"
 	"fmt"
-	"log"
 	"os"
+	"path/filepath"
+	"strings"
 
 	coverage "github.com/rayque/go-coverage/pkg"
 )
 
 var version = "1.0.0"
 
-func main() {
-	inputFile := flag.String("input", "coverage.out", "Path to the coverage file")
-	outputFile := flag.String("output", "coverage.html", "Path to the output HTML file")
-	showVersion := flag.Bool("version", false, "Show version information")
-	quiet := flag.Bool("quiet", false, "Suppress output messages")
-	flag.Usage = func() {
-		fmt.Fprintf(os.Stderr, "Go Coverage HTML Reporter v%s\n\n", version)
-		fmt.Fprintf(os.Stderr, "Usage: go-coverage [options]\n\n")
-		fmt.Fprintf(os.Stderr, "Options:\n")
-		flag.PrintDefaults()
-		fmt.Fprintf(os.Stderr, "\nExamples:\n")
-		fmt.Fprintf(os.Stderr, "  go-coverage\n")
-		fmt.Fprintf(os.Stderr, "  go-coverage -input=coverage.out -output=report.html\n")
-	}
-	flag.Parse()
-	if *showVersion {
-		fmt.Printf("go-coverage v%s\n", version)
-		os.Exit(0)
+var defaultOutputs = map[string]string{
+	"html":      "coverage.html",
+	"cobertura": "coverage.xml",
+	"lcov":      "coverage.info",
+	"json":      "coverage.json",
+	"markdown":  "coverage.md",
+	"sonar":     "sonar-coverage.xml",
+	"text":      "-",
+}
+
+type command struct {
+	name    string
+	summary string
+	run     func(args []string) int
+}
+
+var commands []command
+
+func init() {
+	commands = []command{
+		{"report", "Write an HTML, Cobertura, LCOV, JSON, Markdown, SonarQube or text report (default)", runReport},
+		{"check", "Fail when coverage is below the configured thresholds", runCheck},
+		{"diff", "Report coverage of the lines changed by a diff", runDiff},
+		{"compare", "Show coverage changes between two reports", runCompare},
+		{"merge", "Merge coverage files into a single coverage profile", runMerge},
+		{"convert", "Convert coverage data to a coverage profile or a JSON report", runConvert},
+		{"badge", "Write an SVG coverage badge", runBadge},
+		{"serve", "Serve the HTML report over HTTP", runServe},
+		{"watch", "Re-run tests for changed packages and regenerate the report", runWatch},
+		{"help", "Show help for a command", runHelp},
 	}
-	if _, err := os.Stat(*inputFile); os.IsNotExist(err) {
-		log.Fatalf("Error: Coverage file '%s' does not exist\n", *inputFile)
+}
+
+type stringList []string
+
+func (s *stringList) String() string {
+	return strings.Join(*s, ",")
+}
+
+func (s *stringList) Set(value string) error {
+	*s = append(*s, value)
+	return nil
+}
+
+func expandInputs(patterns []string) ([]string, error) {
+	files := []string{}
+	for _, pattern := range patterns {
+		if !strings.ContainsAny(pattern, "*?[") {
+			if _, err := os.Stat(pattern); os.IsNotExist(err) {
+				return nil, fmt.Errorf("coverage file '%s' does not exist", pattern)
+			}
+			files = append(files, pattern)
+			continue
+		}
+		matches, err := filepath.Glob(pattern)
+		if err != nil {
+			return nil, fmt.Errorf("invalid pattern '%s': %w", pattern, err)
+		}
+		if len(matches) == 0 {
+			return nil, fmt.Errorf("no coverage files match '%s'", pattern)
+		}
+		files = append(files, matches...)
 	}
-	if !*quiet {
-		fmt.Printf("📊 Parsing coverage file: %s\n", *inputFile)
+	return files, nil
+}
+
+func loadReport(inputs stringList, format string, quiet bool) (*coverage.CoverageReport, error) {
+	if len(inputs) == 0 {
+		inputs = stringList{"coverage.out"}
 	}
-	report, err := coverage.ParseCoverageFile(*inputFile)
+	inputFiles, err := expandInputs(inputs)
 	if err != nil {
-		log.Fatalf("Error parsing coverage file: %v\n", err)
+		return nil, err
 	}
-	if !*quiet {
-		totalStmts, coveredStmts, overallPct := report.GetOverallStats()
-		fmt.Printf("📈 Overall coverage: %.1f%% (%d/%d statements)\n", overallPct, coveredStmts, totalStmts)
-		fmt.Printf("📁 Files analyzed: %d\n", len(report.Files))
-		fmt.Printf("🔨 Generating HTML report: %s\n", *outputFile)
+	if !quiet {
+		fmt.Printf("📊 Parsing coverage file: %s\n", strings.Join(inputFiles, ", "))
+	}
+	var report *coverage.CoverageReport
+	switch format {
+	case "text":
+		report, err = coverage.ParseCoverageFiles(inputFiles)
+	case "covdata":
+		report, err = coverage.ParseCoverageDirs(inputFiles)
+	case "json":
+		report, err = loadJSONReports(inputFiles)
+	default:
+		return nil, fmt.Errorf("unknown input format '%s'", format)
 	}
-	err = coverage.GenerateHTMLReport(report, *outputFile)
 	if err != nil {
-		log.Fatalf("Error generating HTML report: %v\n", err)
+		return nil, fmt.Errorf("parsing coverage file: %w", err)
+	}
+	return report, nil
+}
+
+func loadJSONReports(paths []string) (*coverage.CoverageReport, error) {
+	reports := make([]*coverage.CoverageReport, 0, len(paths))
+	for _, path := range paths {
+		report, err := coverage.LoadJSONReport(path)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", path, err)
+		}
+		reports = append(reports, report)
+	}
+	return coverage.MergeReports(reports...)
+}
+
+type globalFlags struct {
+	inputs      stringList
+	inputFormat *string
+	quiet       *bool
+}
+
+func addGlobalFlags(fs *flag.FlagSet, quietUsage string) *globalFlags {
+	g := &globalFlags{}
+	fs.Var(&g.inputs, "input", "Path or glob of a coverage file, repeatable (default \"coverage.out\")")
+	g.inputFormat, g.quiet = addFormatFlags(fs, quietUsage)
+	return g
+}
+
+func addFormatFlags(fs *flag.FlagSet, quietUsage string) (inputFormat *string, quiet *bool) {
+	inputFormat = fs.String("input-format", "text", "Input format: text (coverage profile), covdata (GOCOVERDIR directory) or json (go-coverage JSON report)")
+	quiet = fs.Bool("quiet", false, quietUsage)
+	return inputFormat, quiet
+}
+
+func (g *globalFlags) load() (*coverage.CoverageReport, error) {
+	return loadReport(g.inputs, *g.inputFormat, *g.quiet)
+}
+
+type sourceFlags struct {
+	root    *string
+	archive *string
+	rev     *string
+}
+
+func addSourceFlags(fs *flag.FlagSet) *sourceFlags {
+	return &sourceFlags{
+		root:    fs.String("src-root", ".", "Module root used to resolve source files (reads go.mod, vendor/ and GOMODCACHE)"),
+		archive: fs.String("src-archive", "", "Read source files from a .zip, .tar or .tar.gz archive"),
+		rev:     fs.String("src-rev", "", "Read source files from a git revision of the repository at -src-root"),
+	}
+}
+
+func (f *sourceFlags) resolver() (coverage.SourceResolver, error) {
+	switch {
+	case *f.archive != "":
+		return coverage.NewArchiveResolver(*f.archive)
+	case *f.rev != "":
+		return coverage.NewGitResolver(*f.root, *f.rev)
+	default:
+		return coverage.NewModuleResolver(*f.root)
+	}
+}
+
+func setUsage(fs *flag.FlagSet, usage, description string, examples ...string) {
+	fs.Usage = func() {
+		fmt.Fprintf(os.Stderr, "Usage: %s\n\n", usage)
+		if description != "" {
+			fmt.Fprintf(os.Stderr, "%s\n\n", description)
+		}
+		fmt.Fprintf(os.Stderr, "Options:\n")
+		fs.PrintDefaults()
+		if len(examples) > 0 {
+			fmt.Fprintf(os.Stderr, "\nExamples:\n")
+			for _, example := range examples {
+				fmt.Fprintf(os.Stderr, "  %s\n", example)
+			}
+		}
+	}
+}
+
+func printCommands() {
+	fmt.Fprintf(os.Stderr, "Commands:\n")
+	for _, cmd := range commands {
+		fmt.Fprintf(os.Stderr, "  %-9s %s\n", cmd.name, cmd.summary)
 	}
-	if !*quiet {
-		fmt.Printf("✅ Report generated successfully!\n")
-		fmt.Printf("🌐 Open %s in your browser to view the report\n", *outputFile)
+	fmt.Fprintf(os.Stderr, "\nRun 'go-coverage help <command>' for the options of a command.\n")
+}
+
+func findCommand(name string) (command, bool) {
+	for _, cmd := range commands {
+		if cmd.name == name {
+			return cmd, true
+		}
+	}
+	return command{}, false
+}
+
+func runHelp(args []string) int {
+	if len(args) == 0 {
+		fmt.Fprintf(os.Stderr, "Go Coverage HTML Reporter v%s\n\n", version)
+		fmt.Fprintf(os.Stderr, "Usage: go-coverage [command] [options]\n\n")
+		printCommands()
+		return 0
+	}
+	cmd, ok := findCommand(args[0])
+	if !ok {
+		fmt.Fprintf(os.Stderr, "Error: unknown command '%s'\n\n", args[0])
+		printCommands()
+		return 2
+	}
+	if cmd.name == "help" {
+		return runHelp(nil)
+	}
+	return cmd.run([]string{"-h"})
+}
+
+func run(args []string) int {
+	if len(args) > 0 && !strings.HasPrefix(args[0], "-") {
+		cmd, ok := findCommand(args[0])
+		if !ok {
+			fmt.Fprintf(os.Stderr, "Error: unknown command '%s'\n\n", args[0])
+			printCommands()
+			return 2
+		}
+		return cmd.run(args[1:])
+	}
+	return runReport(args)
+}
+
+func main() {
+	os.Exit(run(os.Args[1:]))
+}
+
+func useColor(f *os.File) bool {
+	if os.Getenv("NO_COLOR") != "" || os.Getenv("TERM") == "dumb" {
+		return false
 	}
+	info, err := f.Stat()
+	return err == nil && info.Mode()&os.ModeCharDevice != 0
 }
@@ -0,0 +1,43 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestRun(t *testing.T) {
+	dir := t.TempDir()
+	profile := filepath.Join(dir, "coverage.out")
+	if err := os.WriteFile(profile, []byte("mode: set\nexample.com/app/a.go:3.14,5.2 1 1\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		name   string
+		args   []string
+		code   int
+		output string
+	}{
+		{"flags without command run report", []string{"-input=" + profile, "-format=json", "-output=" + filepath.Join(dir, "default.json"), "-quiet"}, 0, "default.json"},
+		{"report command", []string{"report", "-input=" + profile, "-format=json", "-output=" + filepath.Join(dir, "report.json"), "-quiet"}, 0, "report.json"},
+		{"merge command", []string{"merge", "-input=" + profile, "-output=" + filepath.Join(dir, "merged.out"), "-quiet"}, 0, "merged.out"},
+		{"missing input", []string{"-input=" + filepath.Join(dir, "missing.out"), "-quiet"}, 1, ""},
+		{"unknown format", []string{"-input=" + profile, "-format=pdf"}, 2, ""},
+		{"unknown command", []string{"bogus"}, 2, ""},
+		{"help", []string{"help"}, 0, ""},
+		{"help for help", []string{"help", "help"}, 0, ""},
+		{"help for unknown command", []string{"help", "bogus"}, 2, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if code := run(tt.args); code != tt.code {
+				t.Errorf("Expected exit code %d for %v, got %d", tt.code, tt.args, code)
+			}
+			if tt.output != "" {
+				if _, err := os.Stat(filepath.Join(dir, tt.output)); err != nil {
+					t.Errorf("Expected %s to be written: %v", tt.output, err)
+				}
+			}
+		})
+	}
+}
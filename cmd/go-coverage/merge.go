@@ -0,0 +1,54 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"os"
+
+	coverage "github.com/rayque/go-coverage/pkg"
+)
+
+func runMerge(args []string) int {
+	fs := flag.NewFlagSet("merge", flag.ExitOnError)
+	global := addGlobalFlags(fs, "Suppress output messages")
+	outputFile := fs.String("output", "-", "Path to the merged coverage profile; - writes to stdout")
+	setUsage(fs, "go-coverage merge -input=<file> -input=<file> [options]",
+		"Merges coverage files into a single coverage profile that go tool cover can read.\nCounts of the same block are added; in set mode a block is covered when any input covers it.",
+		"go-coverage merge -input=unit.out -input=integration.out -output=coverage.out",
+		"go-coverage merge -input='shard-*.out' > coverage.out",
+		"go-coverage merge -input-format=covdata -input=./covdata -input=./covdata-e2e -output=coverage.out")
+	fs.Parse(args)
+	if *outputFile == "-" {
+		*global.quiet = true
+	}
+	report, err := global.load()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		return 1
+	}
+	if err := writeOutput(*outputFile, func(w io.Writer) error { return coverage.WriteCoverageProfile(w, report) }); err != nil {
+		fmt.Fprintf(os.Stderr, "Error writing coverage profile: %v\n", err)
+		return 1
+	}
+	if !*global.quiet {
+		_, _, pct := report.GetOverallStats()
+		fmt.Printf("✅ Merged coverage of %d files (%.1f%%) written to %s\n", len(report.Files), pct, *outputFile)
+	}
+	return 0
+}
+
+func writeOutput(path string, write func(w io.Writer) error) error {
+	if path == "-" {
+		return write(os.Stdout)
+	}
+	file, err := os.Create(path)
+	if err != nil {
+		return fmt.Errorf("failed to create output file: %w", err)
+	}
+	if err := write(file); err != nil {
+		file.Close()
+		return err
+	}
+	return file.Close()
+}
@@ -0,0 +1,205 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"text/tabwriter"
+
+	coverage "github.com/rayque/go-coverage/pkg"
+)
+
+type reportOptions struct {
+	worst     int
+	details   bool
+	rewrites  []coverage.PathRewrite
+	uncovered bool
+	sortBy    string
+	site      bool
+}
+
+type reportRenderer interface {
+	Render(w io.Writer) error
+	Generate(outputPath string) error
+}
+
+func runReport(args []string) int {
+	fs := flag.NewFlagSet("report", flag.ExitOnError)
+	global := addGlobalFlags(fs, "Suppress output messages")
+	format := fs.String("format", "html", "Report format: html, cobertura, lcov, json, markdown, sonar or text")
+	outputFile := fs.String("output", "", "Path to the output file (default \"coverage.html\", \"coverage.xml\" for cobertura, \"coverage.info\" for lcov, \"coverage.json\" for json, \"coverage.md\" for markdown, \"sonar-coverage.xml\" for sonar, stdout for text); - writes to stdout")
+	outputDir := fs.String("output-dir", "", "Write the html report as a multi-page site into this directory instead of a single file")
+	sources := addSourceFlags(fs)
+	showVersion := fs.Bool("version", false, "Show version information")
+	worst := fs.Int("worst", 10, "Number of lowest-covered files listed in the markdown report")
+	details := fs.Bool("details", false, "Add a collapsible per-file table for every package to the markdown report")
+	var rewrites stringList
+	fs.Var(&rewrites, "path-rewrite", "Rewrite file paths in the sonar report as <import-path-prefix>=<repository-path-prefix>, repeatable")
+	uncovered := fs.Bool("uncovered", false, "List uncovered line ranges per file in the text report")
+	sortBy := fs.String("sort", "path", "Row order of the text report: path or coverage")
+	funcMode := fs.Bool("func", false, "Print per-function coverage instead of writing the HTML report")
+	fs.Usage = func() {
+		fmt.Fprintf(os.Stderr, "Go Coverage HTML Reporter v%s\n\n", version)
+		fmt.Fprintf(os.Stderr, "Usage: go-coverage [report] [options]\n")
+		fmt.Fprintf(os.Stderr, "       go-coverage <command> [options]\n\n")
+		fmt.Fprintf(os.Stderr, "Writes a coverage report. This is the default command.\n\n")
+		fmt.Fprintf(os.Stderr, "Options:\n")
+		fs.PrintDefaults()
+		fmt.Fprintf(os.Stderr, "\nExamples:\n")
+		fmt.Fprintf(os.Stderr, "  go-coverage\n")
+		fmt.Fprintf(os.Stderr, "  go-coverage -input=coverage.out -output=report.html\n")
+		fmt.Fprintf(os.Stderr, "  go-coverage -input=coverage.out -output-dir=coverage-site\n")
+		fmt.Fprintf(os.Stderr, "  go-coverage -input=shard1.out -input=shard2.out\n")
+		fmt.Fprintf(os.Stderr, "  go-coverage -input='coverage-*.out'\n")
+		fmt.Fprintf(os.Stderr, "  go-coverage -input-format=covdata -input=./covdata\n")
+		fmt.Fprintf(os.Stderr, "  go-coverage -format=cobertura -output=coverage.xml\n")
+		fmt.Fprintf(os.Stderr, "  go-coverage -format=text -uncovered -sort=coverage\n\n")
+		printCommands()
+	}
+	fs.Parse(args)
+	if fs.NArg() > 0 {
+		fmt.Fprintf(os.Stderr, "Error: unexpected argument '%s'\n\n", fs.Arg(0))
+		fs.Usage()
+		return 2
+	}
+	quiet := global.quiet
+	if *funcMode {
+		*quiet = true
+	}
+	if *showVersion {
+		fmt.Printf("go-coverage v%s\n", version)
+		return 0
+	}
+	if _, ok := defaultOutputs[*format]; !ok {
+		fmt.Fprintf(os.Stderr, "Error: unknown format '%s'\n\n", *format)
+		fs.Usage()
+		return 2
+	}
+	if *outputDir != "" {
+		if *format != "html" {
+			fmt.Fprintf(os.Stderr, "Error: -output-dir is only supported by the html format\n")
+			return 2
+		}
+		if *outputFile != "" {
+			fmt.Fprintf(os.Stderr, "Error: -output and -output-dir cannot be used together\n")
+			return 2
+		}
+		*outputFile = *outputDir
+	}
+	if *outputFile == "" {
+		*outputFile = defaultOutputs[*format]
+	}
+	if *outputFile == "-" {
+		if *format == "html" {
+			fmt.Fprintf(os.Stderr, "Error: the html report cannot be written to stdout\n")
+			return 2
+		}
+		*quiet = true
+	}
+	opts := reportOptions{worst: *worst, details: *details, uncovered: *uncovered, sortBy: *sortBy, site: *outputDir != ""}
+	for _, value := range rewrites {
+		rewrite, err := coverage.ParsePathRewrite(value)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			return 2
+		}
+		opts.rewrites = append(opts.rewrites, rewrite)
+	}
+	report, err := global.load()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		return 1
+	}
+	resolver, err := sources.resolver()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error loading source files: %v\n", err)
+		return 1
+	}
+	if *funcMode {
+		printFunctionCoverage(report, resolver)
+		return 0
+	}
+	if !*quiet {
+		totalStmts, coveredStmts, overallPct := report.GetOverallStats()
+		fmt.Printf("📈 Overall coverage: %.1f%% (%d/%d statements)\n", overallPct, coveredStmts, totalStmts)
+		fmt.Printf("📁 Files analyzed: %d\n", len(report.Files))
+		if *outputDir != "" {
+			fmt.Printf("🔨 Generating html site: %s\n", *outputDir)
+		} else {
+			fmt.Printf("🔨 Generating %s report: %s\n", *format, *outputFile)
+		}
+	}
+	if err := generateReport(*format, *outputFile, report, resolver, opts); err != nil {
+		fmt.Fprintf(os.Stderr, "Error generating %s report: %v\n", *format, err)
+		return 1
+	}
+	if !*quiet {
+		fmt.Printf("✅ Report generated successfully!\n")
+		if *outputDir != "" {
+			fmt.Printf("🌐 Open %s in your browser to view the report\n", filepath.Join(*outputDir, "index.html"))
+		} else if *format == "html" {
+			fmt.Printf("🌐 Open %s in your browser to view the report\n", *outputFile)
+		}
+	}
+	return 0
+}
+
+func printFunctionCoverage(report *coverage.CoverageReport, resolver coverage.SourceResolver) {
+	paths := make([]string, 0, len(report.Files))
+	for path := range report.Files {
+		paths = append(paths, path)
+	}
+	sort.Strings(paths)
+	w := tabwriter.NewWriter(os.Stdout, 1, 8, 1, '\t', 0)
+	for _, path := range paths {
+		fileWithSource, err := coverage.GetFileWithSource(resolver, path, report.Files[path])
+		if err != nil || len(fileWithSource.Lines) == 0 {
+			fmt.Fprintf(os.Stderr, "Warning: source for %s not found, skipping functions\n", path)
+			continue
+		}
+		for _, fn := range fileWithSource.Functions {
+			fmt.Fprintf(w, "%s:%d:\t%s\t%.1f%%\n", path, fn.StartLine, fn.Name, fn.Coverage)
+		}
+	}
+	_, _, overallPct := report.GetOverallStats()
+	fmt.Fprintf(w, "total:\t(statements)\t%.1f%%\n", overallPct)
+	w.Flush()
+}
+
+func generateReport(format, outputFile string, report *coverage.CoverageReport, resolver coverage.SourceResolver, opts reportOptions) error {
+	var renderer reportRenderer
+	switch format {
+	case "cobertura":
+		renderer = &coverage.CoberturaReport{Report: report, Resolver: resolver, Version: version}
+	case "lcov":
+		renderer = &coverage.LCOVReport{Report: report, Resolver: resolver}
+	case "json":
+		renderer = &coverage.JSONReport{Report: report, Resolver: resolver, Version: version}
+	case "markdown":
+		renderer = &coverage.MarkdownReport{Report: report, WorstFiles: opts.worst, Details: opts.details}
+	case "sonar":
+		renderer = &coverage.SonarReport{Report: report, Resolver: resolver, Rewrites: opts.rewrites}
+	case "text":
+		renderer = &coverage.TextReport{
+			Report:        report,
+			Resolver:      resolver,
+			Color:         outputFile == "-" && useColor(os.Stdout),
+			ShowUncovered: opts.uncovered,
+			SortBy:        opts.sortBy,
+		}
+	default:
+		if opts.site {
+			site := &coverage.HTMLSite{Report: report, Resolver: resolver}
+			return site.Generate(outputFile)
+		}
+		htmlGen := &coverage.HTMLReport{Report: report, Resolver: resolver}
+		return htmlGen.Generate(outputFile)
+	}
+	if outputFile == "-" {
+		return renderer.Render(os.Stdout)
+	}
+	return renderer.Generate(outputFile)
+}
@@ -0,0 +1,68 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"net/http"
+	"os"
+	"strings"
+
+	coverage "github.com/rayque/go-coverage/pkg"
+)
+
+func runServe(args []string) int {
+	fs := flag.NewFlagSet("serve", flag.ExitOnError)
+	global := addGlobalFlags(fs, "Suppress output messages")
+	addr := fs.String("addr", "localhost:8080", "Address to listen on")
+	sources := addSourceFlags(fs)
+	setUsage(fs, "go-coverage serve [options]",
+		"Serves the HTML report over HTTP. File pages are rendered on request and the\ncoverage files are read again whenever they change on disk.\n\n"+
+			"Endpoints:\n"+
+			"  /                     HTML report\n"+
+			"  /api/summary          Overall, package and file coverage as JSON\n"+
+			"  /api/files/{path}     Functions, lines and blocks of one file as JSON\n"+
+			"  /events               Server-sent reload events",
+		"go-coverage serve",
+		"go-coverage serve -input=coverage.out -addr=:8080")
+	fs.Parse(args)
+	quiet := global.quiet
+	inputs := global.inputs
+	if len(inputs) == 0 {
+		inputs = stringList{"coverage.out"}
+	}
+	watch, err := expandInputs(inputs)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		return 1
+	}
+	resolver, err := sources.resolver()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error loading source files: %v\n", err)
+		return 1
+	}
+	load := func() (*coverage.CoverageReport, error) {
+		return loadReport(inputs, *global.inputFormat, true)
+	}
+	server, err := coverage.NewServer(load, resolver, watch)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		return 1
+	}
+	if !*quiet {
+		_, _, pct := server.Report().GetOverallStats()
+		fmt.Printf("📈 Overall coverage: %.1f%% (%s)\n", pct, strings.Join(watch, ", "))
+		fmt.Printf("🌐 Serving coverage report on %s\n", serverURL(*addr))
+	}
+	if err := http.ListenAndServe(*addr, server); err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		return 1
+	}
+	return 0
+}
+
+func serverURL(addr string) string {
+	if strings.HasPrefix(addr, ":") {
+		return "http://localhost" + addr + "/"
+	}
+	return "http://" + addr + "/"
+}
@@ -0,0 +1,193 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"os"
+	"os/exec"
+	"os/signal"
+	"path/filepath"
+	"strings"
+	"time"
+
+	coverage "github.com/rayque/go-coverage/pkg"
+)
+
+func runWatch(args []string) int {
+	fs := flag.NewFlagSet("watch", flag.ExitOnError)
+	dir := fs.String("dir", ".", "Module directory to watch and run tests in")
+	format := fs.String("format", "html", "Report format: html, cobertura, lcov, json, markdown, sonar or text")
+	outputFile := fs.String("output", "", "Path to the output file (default depends on -format, see go-coverage -help)")
+	addr := fs.String("addr", "", "Also serve the report on this address and reload open browser tabs after each run")
+	interval := fs.Duration("interval", time.Second, "How often to check .go files for changes")
+	quiet := fs.Bool("quiet", false, "Suppress output messages and test output")
+	setUsage(fs, "go-coverage watch [options]",
+		"Runs go test -coverprofile for the whole module, then polls .go files and\nre-runs the tests of every package with changed files. The new coverage\nreplaces those packages in the report, which is then written again.",
+		"go-coverage watch",
+		"go-coverage watch -addr=localhost:8080",
+		"go-coverage watch -format=text -output=-")
+	fs.Parse(args)
+	if _, ok := defaultOutputs[*format]; !ok {
+		fmt.Fprintf(os.Stderr, "Error: unknown format '%s'\n\n", *format)
+		fs.Usage()
+		return 2
+	}
+	if *outputFile == "" {
+		*outputFile = defaultOutputs[*format]
+	}
+	if *outputFile == "-" && *format == "html" {
+		fmt.Fprintf(os.Stderr, "Error: the html report cannot be written to stdout\n")
+		return 2
+	}
+	root, err := filepath.Abs(*dir)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		return 1
+	}
+	if *outputFile != "-" {
+		if *outputFile, err = filepath.Abs(*outputFile); err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			return 1
+		}
+	}
+	resolver, err := coverage.NewModuleResolver(root)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error loading source files: %v\n", err)
+		return 1
+	}
+	tmpDir, err := os.MkdirTemp("", "go-coverage-watch-")
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		return 1
+	}
+	defer os.RemoveAll(tmpDir)
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+	var testOutput io.Writer = os.Stdout
+	if *quiet {
+		testOutput = io.Discard
+	}
+	var ln net.Listener
+	if *addr != "" {
+		if ln, err = net.Listen("tcp", *addr); err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			return 1
+		}
+		defer ln.Close()
+	}
+	profile := filepath.Join(tmpDir, "coverage.out")
+	stamps, err := coverage.ScanGoFiles(root)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		return 1
+	}
+	if !*quiet {
+		fmt.Printf("🧪 Running tests: ./...\n")
+	}
+	report, err := runCoverageTests(ctx, root, profile, []string{"./..."}, testOutput)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		return 1
+	}
+	var server *coverage.Server
+	if ln != nil {
+		server, err = coverage.NewServer(func() (*coverage.CoverageReport, error) { return report, nil }, resolver, nil)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			return 1
+		}
+		go func() {
+			if err := http.Serve(ln, server); err != nil && ctx.Err() == nil {
+				fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			}
+		}()
+		if !*quiet {
+			fmt.Printf("🌐 Serving coverage report on %s\n", serverURL(*addr))
+		}
+	}
+	writeWatchReport(*format, *outputFile, report, resolver, *quiet)
+	ticker := time.NewTicker(*interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return 0
+		case <-ticker.C:
+		}
+		current, err := coverage.ScanGoFiles(root)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			continue
+		}
+		packages, removed := coverage.ChangedPackages(root, stamps, current)
+		stamps = current
+		if len(packages) == 0 && len(removed) == 0 {
+			continue
+		}
+		update := &coverage.CoverageReport{Mode: report.Mode, Files: map[string]*coverage.FileCoverage{}}
+		if len(packages) > 0 {
+			if !*quiet {
+				fmt.Printf("🧪 Running tests: %s\n", strings.Join(packages, " "))
+			}
+			update, err = runCoverageTests(ctx, root, profile, packages, testOutput)
+			if err != nil {
+				if ctx.Err() != nil {
+					return 0
+				}
+				fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+				continue
+			}
+		}
+		removedPaths := make([]string, 0, len(removed))
+		for _, pkg := range removed {
+			removedPaths = append(removedPaths, coverage.PackageImportPath(resolver.ModulePath, pkg))
+		}
+		if !*quiet && len(removed) > 0 {
+			fmt.Printf("🗑️  Removed packages: %s\n", strings.Join(removed, " "))
+		}
+		merged, err := coverage.OverlayReport(report, update, removedPaths...)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error merging coverage: %v\n", err)
+			continue
+		}
+		report = merged
+		writeWatchReport(*format, *outputFile, report, resolver, *quiet)
+		if server != nil {
+			server.SetReport(report)
+		}
+	}
+}
+
+func writeWatchReport(format, outputFile string, report *coverage.CoverageReport, resolver coverage.SourceResolver, quiet bool) {
+	if err := generateReport(format, outputFile, report, resolver, reportOptions{worst: 10, sortBy: "path"}); err != nil {
+		fmt.Fprintf(os.Stderr, "Error generating %s report: %v\n", format, err)
+		return
+	}
+	if !quiet && outputFile != "-" {
+		totalStmts, coveredStmts, overallPct := report.GetOverallStats()
+		fmt.Printf("📈 Overall coverage: %.1f%% (%d/%d statements), written to %s\n", overallPct, coveredStmts, totalStmts, outputFile)
+	}
+}
+
+func runCoverageTests(ctx context.Context, root, profile string, packages []string, output io.Writer) (*coverage.CoverageReport, error) {
+	os.Remove(profile)
+	cmd := exec.CommandContext(ctx, "go", append([]string{"test", "-coverprofile=" + profile}, packages...)...)
+	cmd.Dir = root
+	cmd.Stdout = output
+	cmd.Stderr = output
+	runErr := cmd.Run()
+	if _, err := os.Stat(profile); err != nil {
+		if runErr != nil {
+			return nil, fmt.Errorf("go test: %w", runErr)
+		}
+		return nil, fmt.Errorf("go test did not write a coverage profile")
+	}
+	if runErr != nil {
+		fmt.Fprintf(os.Stderr, "⚠️  go test: %v, using the coverage of the packages that ran\n", runErr)
+	}
+	return coverage.ParseCoverageFile(profile)
+}
@@ -0,0 +1,87 @@
+package coverage
+import (
+"fmt"
+"html"
+"io"
+"math"
+"os"
+)
+type Badge struct {
+Label    string
+Coverage float64
+Style    string
+}
+func GenerateBadge(report *CoverageReport, outputPath string) error {
+_, _, pct := report.GetOverallStats()
+badge := &Badge{Label: "coverage", Coverage: pct}
+return badge.Generate(outputPath)
+}
+func (b *Badge) Generate(outputPath string) error {
+file, err := os.Create(outputPath)
+if err != nil {
+return fmt.Errorf("failed to create output file: %w", err)
+}
+defer file.Close()
+return b.Render(file)
+}
+func (b *Badge) Render(w io.Writer) error {
+label := b.Label
+if label == "" {
+label = "coverage"
+}
+value := FormatPercentage(b.Coverage)
+radius, gradient := 3, true
+switch b.Style {
+case "", "flat":
+case "flat-square":
+radius, gradient = 0, false
+default:
+return fmt.Errorf("unknown badge style '%s'", b.Style)
+}
+labelWidth := badgeTextWidth(label) + 10
+valueWidth := badgeTextWidth(value) + 10
+width := labelWidth + valueWidth
+text := html.EscapeString(label)
+title := html.EscapeString(label + ": " + value)
+fmt.Fprintf(w, `<svg xmlns="http://www.w3.org/2000/svg" width="%d" height="20" role="img" aria-label="%s">`, width, title)
+fmt.Fprintf(w, `<title>%s</title>`, title)
+if gradient {
+fmt.Fprint(w, `<linearGradient id="s" x2="0" y2="100%"><stop offset="0" stop-color="#bbb" stop-opacity=".1"/><stop offset="1" stop-opacity=".1"/></linearGradient>`)
+}
+fmt.Fprintf(w, `<clipPath id="r"><rect width="%d" height="20" rx="%d" fill="#fff"/></clipPath>`, width, radius)
+fmt.Fprintf(w, `<g clip-path="url(#r)"><rect width="%d" height="20" fill="#555"/><rect x="%d" width="%d" height="20" fill="%s"/>`, labelWidth, labelWidth, valueWidth, GetCoverageColor(b.Coverage))
+if gradient {
+fmt.Fprintf(w, `<rect width="%d" height="20" fill="url(#s)"/>`, width)
+}
+fmt.Fprint(w, `</g><g fill="#fff" text-anchor="middle" font-family="Verdana,Geneva,DejaVu Sans,sans-serif" font-size="11">`)
+for _, part := range []struct {
+x    float64
+text string
+}{{float64(labelWidth) / 2, text}, {float64(labelWidth) + float64(valueWidth)/2, value}} {
+fmt.Fprintf(w, `<text x="%.1f" y="15" fill="#010101" fill-opacity=".3">%s</text><text x="%.1f" y="14">%s</text>`, part.x, part.text, part.x, part.text)
+}
+_, err := fmt.Fprint(w, "</g></svg>\n")
+return err
+}
+func badgeTextWidth(s string) int {
+width := 0.0
+for _, r := range s {
+switch {
+case r == 'i' || r == 'l' || r == 'j' || r == '.' || r == ',' || r == ':' || r == '\'' || r == '|':
+width += 3.5
+case r == ' ' || r == 'f' || r == 't' || r == 'r' || r == 'I' || r == '-' || r == '(' || r == ')':
+width += 4.5
+case r == 'm' || r == 'w' || r == 'M' || r == 'W':
+width += 10
+case r == '%':
+width += 12
+case r >= 'A' && r <= 'Z':
+width += 7.5
+case r >= '0' && r <= '9':
+width += 7
+default:
+width += 6.5
+}
+}
+return int(math.Ceil(width))
+}
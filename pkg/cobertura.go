@@ -0,0 +1,174 @@
+package coverage
+import (
+"encoding/xml"
+"fmt"
+"io"
+"os"
+"path"
+"path/filepath"
+"strings"
+"time"
+)
+const coberturaDoctype = `<!DOCTYPE coverage SYSTEM "http://cobertura.sourceforge.net/xml/coverage-04.dtd">`
+type CoberturaReport struct {
+Report   *CoverageReport
+Resolver SourceResolver
+Version  string
+}
+type coberturaCoverage struct {
+XMLName         xml.Name           `xml:"coverage"`
+LineRate        float64            `xml:"line-rate,attr"`
+BranchRate      float64            `xml:"branch-rate,attr"`
+LinesCovered    int                `xml:"lines-covered,attr"`
+LinesValid      int                `xml:"lines-valid,attr"`
+BranchesCovered int                `xml:"branches-covered,attr"`
+BranchesValid   int                `xml:"branches-valid,attr"`
+Complexity      float64            `xml:"complexity,attr"`
+Version         string             `xml:"version,attr"`
+Timestamp       int64              `xml:"timestamp,attr"`
+Sources         []string           `xml:"sources>source"`
+Packages        []coberturaPackage `xml:"packages>package"`
+}
+type coberturaPackage struct {
+Name       string           `xml:"name,attr"`
+LineRate   float64          `xml:"line-rate,attr"`
+BranchRate float64          `xml:"branch-rate,attr"`
+Complexity float64          `xml:"complexity,attr"`
+Classes    []coberturaClass `xml:"classes>class"`
+}
+type coberturaClass struct {
+Name       string            `xml:"name,attr"`
+FileName   string            `xml:"filename,attr"`
+LineRate   float64           `xml:"line-rate,attr"`
+BranchRate float64           `xml:"branch-rate,attr"`
+Complexity float64           `xml:"complexity,attr"`
+Methods    []coberturaMethod `xml:"methods>method"`
+Lines      []coberturaLine   `xml:"lines>line"`
+}
+type coberturaMethod struct {
+Name       string          `xml:"name,attr"`
+Signature  string          `xml:"signature,attr"`
+LineRate   float64         `xml:"line-rate,attr"`
+BranchRate float64         `xml:"branch-rate,attr"`
+Complexity float64         `xml:"complexity,attr"`
+Lines      []coberturaLine `xml:"lines>line"`
+}
+type coberturaLine struct {
+Number int  `xml:"number,attr"`
+Hits   int  `xml:"hits,attr"`
+Branch bool `xml:"branch,attr"`
+}
+func GenerateCoberturaReport(report *CoverageReport, outputPath string) error {
+cobertura := &CoberturaReport{Report: report}
+return cobertura.Generate(outputPath)
+}
+func (c *CoberturaReport) Generate(outputPath string) error {
+file, err := os.Create(outputPath)
+if err != nil {
+return fmt.Errorf("failed to create output file: %w", err)
+}
+defer file.Close()
+return c.Render(file)
+}
+func (c *CoberturaReport) Render(w io.Writer) error {
+resolver := c.Resolver
+if resolver == nil {
+resolver = defaultSourceResolver()
+}
+modulePath := sourceModulePath(resolver)
+doc := coberturaCoverage{
+Version:   c.Version,
+Timestamp: time.Now().UnixMilli(),
+Sources:   []string{sourceRoot(resolver)},
+Packages:  []coberturaPackage{},
+}
+for _, pkg := range c.Report.Packages() {
+cp := coberturaPackage{Name: pkg.Path, Classes: []coberturaClass{}}
+pkgValid, pkgCovered := 0, 0
+for _, fc := range pkg.Files {
+class, valid, covered := coberturaFile(resolver, fc, modulePath)
+cp.Classes = append(cp.Classes, class)
+pkgValid += valid
+pkgCovered += covered
+}
+cp.LineRate = lineRate(pkgCovered, pkgValid)
+doc.LinesValid += pkgValid
+doc.LinesCovered += pkgCovered
+doc.Packages = append(doc.Packages, cp)
+}
+doc.LineRate = lineRate(doc.LinesCovered, doc.LinesValid)
+if _, err := io.WriteString(w, xml.Header+coberturaDoctype+"\n"); err != nil {
+return err
+}
+enc := xml.NewEncoder(w)
+enc.Indent("", "  ")
+if err := enc.Encode(doc); err != nil {
+return fmt.Errorf("failed to encode cobertura report: %w", err)
+}
+_, err := io.WriteString(w, "\n")
+return err
+}
+func coberturaFile(resolver SourceResolver, coverage *FileCoverage, modulePath string) (coberturaClass, int, int) {
+fileName := coverage.FileName
+if rel, ok := trimModulePrefix(fileName, modulePath); ok {
+fileName = rel
+}
+class := coberturaClass{
+Name:     strings.TrimSuffix(path.Base(coverage.FileName), ".go"),
+FileName: fileName,
+Methods:  []coberturaMethod{},
+Lines:    []coberturaLine{},
+}
+lines := linesFromBlocks(coverage)
+var functions []FunctionCoverage
+if fileWithSource, err := GetFileWithSource(resolver, coverage.FileName, coverage); err == nil && len(fileWithSource.Lines) > 0 {
+lines = fileWithSource.Lines
+functions = fileWithSource.Functions
+}
+valid, covered := 0, 0
+for _, line := range lines {
+if !line.Instrumented {
+continue
+}
+class.Lines = append(class.Lines, coberturaLine{Number: line.LineNumber, Hits: line.Count})
+valid++
+if line.IsCovered {
+covered++
+}
+}
+class.LineRate = lineRate(covered, valid)
+for _, fn := range functions {
+if fn.IsLiteral {
+continue
+}
+method := coberturaMethod{Name: fn.Name, Lines: []coberturaLine{}}
+methodCovered := 0
+for _, line := range class.Lines {
+if line.Number < fn.StartLine || line.Number > fn.EndLine {
+continue
+}
+method.Lines = append(method.Lines, line)
+if line.Hits > 0 {
+methodCovered++
+}
+}
+method.LineRate = lineRate(methodCovered, len(method.Lines))
+class.Methods = append(class.Methods, method)
+}
+return class, valid, covered
+}
+func lineRate(covered, valid int) float64 {
+if valid == 0 {
+return 0
+}
+return float64(covered) / float64(valid)
+}
+func sourceRoot(resolver SourceResolver) string {
+if r, ok := resolver.(*ModuleResolver); ok {
+if abs, err := filepath.Abs(r.Root); err == nil {
+return abs
+}
+return r.Root
+}
+return "."
+}
@@ -0,0 +1,350 @@
+package coverage
+import (
+"fmt"
+"io"
+"math"
+"os"
+"sort"
+"strings"
+"text/tabwriter"
+)
+const maxLineEdits = 2000
+type FileDelta struct {
+Path           string
+Status         string
+BaseTotal      int
+BaseCovered    int
+BaseCoverage   float64
+HeadTotal      int
+HeadCovered    int
+HeadCoverage   float64
+Delta          float64
+NewlyUncovered []int
+Unmatched      bool
+}
+type PackageDelta struct {
+Path         string
+Status       string
+BaseTotal    int
+BaseCovered  int
+BaseCoverage float64
+HeadTotal    int
+HeadCovered  int
+HeadCoverage float64
+Delta        float64
+}
+type ReportComparison struct {
+BaseTotal    int
+BaseCovered  int
+BaseCoverage float64
+HeadTotal    int
+HeadCovered  int
+HeadCoverage float64
+Delta        float64
+Files        []FileDelta
+Packages     []PackageDelta
+}
+func CompareReports(base, head *CoverageReport) *ReportComparison {
+return CompareReportsWithSources(base, head, nil, nil)
+}
+func CompareReportsWithSources(base, head *CoverageReport, baseSources, headSources SourceResolver) *ReportComparison {
+c := &ReportComparison{}
+c.BaseTotal, c.BaseCovered, c.BaseCoverage = base.GetOverallStats()
+c.HeadTotal, c.HeadCovered, c.HeadCoverage = head.GetOverallStats()
+c.Delta = c.HeadCoverage - c.BaseCoverage
+paths := map[string]bool{}
+for p := range base.Files {
+paths[p] = true
+}
+for p := range head.Files {
+paths[p] = true
+}
+for p := range paths {
+delta := FileDelta{Path: p}
+baseFile, inBase := base.Files[p]
+headFile, inHead := head.Files[p]
+if inBase {
+delta.BaseTotal, delta.BaseCovered, delta.BaseCoverage = baseFile.GetCoverageStats()
+}
+if inHead {
+delta.HeadTotal, delta.HeadCovered, delta.HeadCoverage = headFile.GetCoverageStats()
+if !inBase {
+delta.NewlyUncovered = newlyUncoveredLines(nil, linesFromBlocks(headFile), nil)
+} else if match, ok := matchFileLines(p, baseFile, headFile, baseSources, headSources); ok {
+delta.NewlyUncovered = newlyUncoveredLines(linesFromBlocks(baseFile), linesFromBlocks(headFile), match)
+} else {
+delta.Unmatched = true
+}
+}
+delta.Status = deltaStatus(inBase, inHead, delta.BaseCoverage, delta.HeadCoverage)
+delta.Delta = delta.HeadCoverage - delta.BaseCoverage
+c.Files = append(c.Files, delta)
+}
+sort.Slice(c.Files, func(i, j int) bool {
+return c.Files[i].Path < c.Files[j].Path
+})
+basePackages := map[string]*PackageCoverage{}
+for _, pkg := range base.Packages() {
+basePackages[pkg.Path] = pkg
+}
+headPackages := map[string]*PackageCoverage{}
+for _, pkg := range head.Packages() {
+headPackages[pkg.Path] = pkg
+}
+pkgPaths := map[string]bool{}
+for p := range basePackages {
+pkgPaths[p] = true
+}
+for p := range headPackages {
+pkgPaths[p] = true
+}
+for p := range pkgPaths {
+delta := PackageDelta{Path: p}
+basePkg, inBase := basePackages[p]
+headPkg, inHead := headPackages[p]
+if inBase {
+delta.BaseTotal, delta.BaseCovered, delta.BaseCoverage = basePkg.GetCoverageStats()
+}
+if inHead {
+delta.HeadTotal, delta.HeadCovered, delta.HeadCoverage = headPkg.GetCoverageStats()
+}
+delta.Status = deltaStatus(inBase, inHead, delta.BaseCoverage, delta.HeadCoverage)
+delta.Delta = delta.HeadCoverage - delta.BaseCoverage
+c.Packages = append(c.Packages, delta)
+}
+sort.Slice(c.Packages, func(i, j int) bool {
+return c.Packages[i].Path < c.Packages[j].Path
+})
+return c
+}
+func deltaStatus(inBase, inHead bool, basePct, headPct float64) string {
+switch {
+case !inBase:
+return "added"
+case !inHead:
+return "removed"
+case math.Abs(headPct-basePct) < 0.05:
+return "unchanged"
+case headPct > basePct:
+return "increased"
+default:
+return "decreased"
+}
+}
+func newlyUncoveredLines(base, head []LineCoverage, match []int) []int {
+lines := []int{}
+for _, line := range head {
+if !line.Instrumented || line.IsCovered {
+continue
+}
+n := line.LineNumber
+if match != nil {
+n = 0
+if line.LineNumber <= len(match) {
+n = match[line.LineNumber-1]
+}
+}
+if n > 0 && n <= len(base) && base[n-1].Instrumented && !base[n-1].IsCovered {
+continue
+}
+lines = append(lines, line.LineNumber)
+}
+return lines
+}
+func matchFileLines(path string, baseFile, headFile *FileCoverage, baseSources, headSources SourceResolver) ([]int, bool) {
+if baseSources != nil && headSources != nil {
+baseLines, headLines := readSourceLines(baseSources, path), readSourceLines(headSources, path)
+if baseLines != nil && headLines != nil {
+if match := matchLines(baseLines, headLines); match != nil {
+return match, true
+}
+}
+}
+return nil, sameBlockLayout(baseFile, headFile)
+}
+func readSourceLines(resolver SourceResolver, path string) []string {
+file, err := resolver.Open(path)
+if err != nil {
+return nil
+}
+defer file.Close()
+src, err := io.ReadAll(file)
+if err != nil {
+return nil
+}
+return strings.Split(strings.TrimSuffix(string(src), "\n"), "\n")
+}
+func sameBlockLayout(a, b *FileCoverage) bool {
+layout := func(fc *FileCoverage) []CoverageBlock {
+blocks := make([]CoverageBlock, len(fc.Blocks))
+for i, block := range fc.Blocks {
+block.Count = 0
+blocks[i] = block
+}
+sort.Slice(blocks, func(i, j int) bool {
+if blocks[i].StartLine != blocks[j].StartLine {
+return blocks[i].StartLine < blocks[j].StartLine
+}
+return blocks[i].StartCol < blocks[j].StartCol
+})
+return blocks
+}
+la, lb := layout(a), layout(b)
+if len(la) != len(lb) {
+return false
+}
+for i := range la {
+if la[i] != lb[i] {
+return false
+}
+}
+return true
+}
+func matchLines(a, b []string) []int {
+n, m := len(a), len(b)
+max := n + m
+if max > maxLineEdits {
+max = maxLineEdits
+}
+offset := max + 1
+v := make([]int, 2*max+3)
+trace := [][]int{}
+for d := 0; d <= max; d++ {
+trace = append(trace, append([]int(nil), v[offset-d-1:offset+d+2]...))
+for k := -d; k <= d; k += 2 {
+x := 0
+if k == -d || (k != d && v[offset+k-1] < v[offset+k+1]) {
+x = v[offset+k+1]
+} else {
+x = v[offset+k-1] + 1
+}
+y := x - k
+for x < n && y < m && a[x] == b[y] {
+x++
+y++
+}
+v[offset+k] = x
+if x >= n && y >= m {
+return backtrackLines(trace, n, m)
+}
+}
+}
+return nil
+}
+func backtrackLines(trace [][]int, x, y int) []int {
+match := make([]int, y)
+for d := len(trace) - 1; d >= 0; d-- {
+v := trace[d]
+k := x - y
+prevK := k - 1
+if k == -d || (k != d && v[k-1+d+1] < v[k+1+d+1]) {
+prevK = k + 1
+}
+prevX := v[prevK+d+1]
+prevY := prevX - prevK
+for x > prevX && y > prevY && x > 0 && y > 0 {
+x--
+y--
+match[y] = x + 1
+}
+x, y = prevX, prevY
+}
+return match
+}
+func (c *ReportComparison) FilesWithStatus(status string) []FileDelta {
+files := []FileDelta{}
+for _, f := range c.Files {
+if f.Status == status {
+files = append(files, f)
+}
+}
+return files
+}
+func FormatDelta(delta float64) string {
+if math.Abs(delta) < 0.05 {
+return "±0.0%"
+}
+return fmt.Sprintf("%+.1f%%", delta)
+}
+func DeltaArrow(delta float64) string {
+switch {
+case math.Abs(delta) < 0.05:
+return "–"
+case delta > 0:
+return "▲"
+default:
+return "▼"
+}
+}
+func WriteComparisonText(w io.Writer, c *ReportComparison) error {
+tw := tabwriter.NewWriter(w, 0, 8, 2, ' ', 0)
+fmt.Fprintf(tw, "PACKAGE\tBASE\tHEAD\tDELTA\n")
+for _, p := range c.Packages {
+fmt.Fprintf(tw, "%s\t%s\t%s\t%s\n", p.Path, formatSide(p.Status != "added", p.BaseCoverage), formatSide(p.Status != "removed", p.HeadCoverage), formatStatusDelta(p.Status, p.Delta))
+}
+fmt.Fprintf(tw, "\t\t\t\n")
+fmt.Fprintf(tw, "FILE\tBASE\tHEAD\tDELTA\n")
+for _, f := range c.Files {
+if f.Status == "unchanged" && len(f.NewlyUncovered) == 0 {
+continue
+}
+fmt.Fprintf(tw, "%s\t%s\t%s\t%s\n", f.Path, formatSide(f.Status != "added", f.BaseCoverage), formatSide(f.Status != "removed", f.HeadCoverage), formatStatusDelta(f.Status, f.Delta))
+}
+fmt.Fprintf(tw, "TOTAL\t%s\t%s\t%s %s\n", FormatPercentage(c.BaseCoverage), FormatPercentage(c.HeadCoverage), DeltaArrow(c.Delta), FormatDelta(c.Delta))
+if err := tw.Flush(); err != nil {
+return err
+}
+uncovered := false
+for _, f := range c.Files {
+if len(f.NewlyUncovered) == 0 {
+continue
+}
+if !uncovered {
+fmt.Fprintf(w, "\nNewly uncovered lines:\n")
+uncovered = true
+}
+fmt.Fprintf(w, "  %s: %s\n", f.Path, FormatLineRanges(f.NewlyUncovered))
+}
+unmatched := false
+for _, f := range c.Files {
+if !f.Unmatched {
+continue
+}
+if !unmatched {
+fmt.Fprintf(w, "\nNewly uncovered lines not computed because the sources changed:\n")
+unmatched = true
+}
+fmt.Fprintf(w, "  %s: sources changed\n", f.Path)
+}
+return nil
+}
+func formatSide(present bool, pct float64) string {
+if !present {
+return "-"
+}
+return FormatPercentage(pct)
+}
+func formatStatusDelta(status string, delta float64) string {
+switch status {
+case "added":
+return "new"
+case "removed":
+return "removed"
+}
+return DeltaArrow(delta) + " " + FormatDelta(delta)
+}
+func GenerateComparisonHTML(c *ReportComparison, outputPath string) error {
+file, err := os.Create(outputPath)
+if err != nil {
+return fmt.Errorf("failed to create output file: %w", err)
+}
+defer file.Close()
+tmpl, err := parseHTMLTemplate("compare", compareTemplateContent)
+if err != nil {
+return err
+}
+if err := tmpl.Execute(file, c); err != nil {
+return fmt.Errorf("failed to execute template: %w", err)
+}
+return nil
+}
@@ -0,0 +1,399 @@
+package coverage
+import (
+"bytes"
+"encoding/binary"
+"encoding/hex"
+"fmt"
+"os"
+"path/filepath"
+"sort"
+"strings"
+)
+var (
+covMetaMagic    = []byte{0x00, 'c', 'v', 'm'}
+covCounterMagic = []byte{0x00, 'c', 'w', 'm'}
+)
+const (
+covMetaFileHeaderSize    = 56
+covMetaPackageHeaderSize = 44
+covCounterHeaderSize     = 32
+covCounterFooterSize     = 16
+covCounterFlavorRaw      = 1
+covCounterFlavorULeb128  = 2
+covGranularityPerFunc    = 2
+)
+type covFunc struct {
+file  string
+units []CoverageBlock
+}
+type covFuncKey struct {
+pkg, fn uint32
+}
+type covMetaFile struct {
+hash        string
+mode        string
+granularity uint8
+funcs       map[covFuncKey]covFunc
+}
+func ParseCoverageDirs(dirs []string) (*CoverageReport, error) {
+if len(dirs) == 0 {
+return nil, fmt.Errorf("no coverage directories given")
+}
+reports := make([]*CoverageReport, 0, len(dirs))
+for _, dir := range dirs {
+report, err := ParseCoverageDir(dir)
+if err != nil {
+return nil, fmt.Errorf("%s: %w", dir, err)
+}
+reports = append(reports, report)
+}
+return MergeReports(reports...)
+}
+func ParseCoverageDir(dir string) (*CoverageReport, error) {
+entries, err := os.ReadDir(dir)
+if err != nil {
+return nil, fmt.Errorf("failed to read coverage directory: %w", err)
+}
+metaFiles := []string{}
+counterFiles := make(map[string][]string)
+for _, entry := range entries {
+name := entry.Name()
+if entry.IsDir() {
+continue
+}
+switch {
+case strings.HasPrefix(name, "covmeta."):
+metaFiles = append(metaFiles, name)
+case strings.HasPrefix(name, "covcounters."):
+parts := strings.Split(name, ".")
+if len(parts) == 4 {
+counterFiles[parts[1]] = append(counterFiles[parts[1]], name)
+}
+}
+}
+if len(metaFiles) == 0 {
+return nil, fmt.Errorf("no covmeta files found in %s", dir)
+}
+sort.Strings(metaFiles)
+reports := []*CoverageReport{}
+for _, name := range metaFiles {
+meta, err := readCovMetaFile(filepath.Join(dir, name))
+if err != nil {
+return nil, fmt.Errorf("%s: %w", name, err)
+}
+counters := make(map[covFuncKey][]int)
+files := counterFiles[meta.hash]
+sort.Strings(files)
+for _, counterName := range files {
+if err := readCovCounterFile(filepath.Join(dir, counterName), meta, counters); err != nil {
+return nil, fmt.Errorf("%s: %w", counterName, err)
+}
+}
+report, err := meta.report(counters)
+if err != nil {
+return nil, fmt.Errorf("%s: %w", name, err)
+}
+reports = append(reports, report)
+}
+return MergeReports(reports...)
+}
+func (m *covMetaFile) report(counters map[covFuncKey][]int) (*CoverageReport, error) {
+report := &CoverageReport{
+Mode:  m.mode,
+Files: make(map[string]*FileCoverage),
+}
+keys := make([]covFuncKey, 0, len(m.funcs))
+for key := range m.funcs {
+keys = append(keys, key)
+}
+sort.Slice(keys, func(i, j int) bool {
+if keys[i].pkg != keys[j].pkg {
+return keys[i].pkg < keys[j].pkg
+}
+return keys[i].fn < keys[j].fn
+})
+for _, key := range keys {
+fn := m.funcs[key]
+values := counters[key]
+if _, exists := report.Files[fn.file]; !exists {
+report.Files[fn.file] = &FileCoverage{
+FileName: fn.file,
+Blocks:   []CoverageBlock{},
+}
+}
+for i, unit := range fn.units {
+switch {
+case m.granularity == covGranularityPerFunc && len(values) > 0:
+unit.Count = values[0]
+case i < len(values):
+unit.Count = values[i]
+}
+report.Files[fn.file].Blocks = append(report.Files[fn.file].Blocks, unit)
+}
+}
+for fileName, fc := range report.Files {
+blocks, err := mergeBlocks(report.Mode, fc.Blocks)
+if err != nil {
+return nil, fmt.Errorf("%s: %w", fileName, err)
+}
+fc.Blocks = blocks
+}
+return report, nil
+}
+type covReader struct {
+data []byte
+off  int
+err  error
+}
+func (r *covReader) need(n int) bool {
+if r.err != nil {
+return false
+}
+if n < 0 || r.off+n > len(r.data) {
+r.err = fmt.Errorf("unexpected end of data at offset %d", r.off)
+return false
+}
+return true
+}
+func (r *covReader) bytes(n int) []byte {
+if !r.need(n) {
+return nil
+}
+b := r.data[r.off : r.off+n]
+r.off += n
+return b
+}
+func (r *covReader) uint32() uint32 {
+if !r.need(4) {
+return 0
+}
+v := binary.LittleEndian.Uint32(r.data[r.off:])
+r.off += 4
+return v
+}
+func (r *covReader) uint64() uint64 {
+if !r.need(8) {
+return 0
+}
+v := binary.LittleEndian.Uint64(r.data[r.off:])
+r.off += 8
+return v
+}
+func (r *covReader) uleb128() uint64 {
+var value uint64
+var shift uint
+for {
+if !r.need(1) {
+return 0
+}
+b := r.data[r.off]
+r.off++
+value |= uint64(b&0x7f) << shift
+if b&0x80 == 0 {
+return value
+}
+shift += 7
+}
+}
+func (r *covReader) stringTable() []string {
+n := int(r.uleb128())
+strs := []string{}
+for i := 0; i < n && r.err == nil; i++ {
+size := int(r.uleb128())
+strs = append(strs, string(r.bytes(size)))
+}
+return strs
+}
+func readCovMetaFile(path string) (*covMetaFile, error) {
+data, err := os.ReadFile(path)
+if err != nil {
+return nil, err
+}
+if len(data) < covMetaFileHeaderSize || !bytes.Equal(data[:4], covMetaMagic) {
+return nil, fmt.Errorf("not a coverage meta-data file")
+}
+r := &covReader{data: data, off: 4}
+if version := r.uint32(); version != 1 {
+return nil, fmt.Errorf("unsupported meta-data file version %d", version)
+}
+r.uint64()
+entries := r.uint64()
+hash := r.bytes(16)
+r.off = 48
+meta := &covMetaFile{
+hash:        hex.EncodeToString(hash),
+mode:        covCounterMode(data[48]),
+granularity: data[49],
+funcs:       make(map[covFuncKey]covFunc),
+}
+if meta.mode == "" {
+return nil, fmt.Errorf("unsupported counter mode %d", data[48])
+}
+r.off = covMetaFileHeaderSize
+if entries > uint64(len(data)) {
+return nil, fmt.Errorf("invalid package count %d", entries)
+}
+offsets := make([]uint64, entries)
+for i := range offsets {
+offsets[i] = r.uint64()
+}
+lengths := make([]uint64, entries)
+for i := range lengths {
+lengths[i] = r.uint64()
+}
+if r.err != nil {
+return nil, r.err
+}
+for pkg := range offsets {
+if offsets[pkg]+lengths[pkg] > uint64(len(data)) {
+return nil, fmt.Errorf("package %d extends past end of file", pkg)
+}
+payload := data[offsets[pkg] : offsets[pkg]+lengths[pkg]]
+if err := meta.readPackage(uint32(pkg), payload); err != nil {
+return nil, fmt.Errorf("package %d: %w", pkg, err)
+}
+}
+return meta, nil
+}
+func (m *covMetaFile) readPackage(pkg uint32, payload []byte) error {
+if len(payload) < covMetaPackageHeaderSize {
+return fmt.Errorf("truncated package header")
+}
+r := &covReader{data: payload, off: 40}
+numFuncs := int(r.uint32())
+if numFuncs > len(payload) {
+return fmt.Errorf("invalid function count %d", numFuncs)
+}
+funcOffsets := make([]uint32, numFuncs)
+for i := range funcOffsets {
+funcOffsets[i] = r.uint32()
+}
+strs := r.stringTable()
+if r.err != nil {
+return r.err
+}
+str := func(idx uint64) string {
+if idx < uint64(len(strs)) {
+return strs[idx]
+}
+r.err = fmt.Errorf("invalid string table index %d", idx)
+return ""
+}
+for fn, off := range funcOffsets {
+r.off = int(off)
+numUnits := int(r.uleb128())
+r.uleb128()
+file := str(r.uleb128())
+if numUnits > len(payload) {
+return fmt.Errorf("invalid unit count %d", numUnits)
+}
+units := make([]CoverageBlock, 0, numUnits)
+for i := 0; i < numUnits; i++ {
+units = append(units, CoverageBlock{
+StartLine: int(r.uleb128()),
+StartCol:  int(r.uleb128()),
+EndLine:   int(r.uleb128()),
+EndCol:    int(r.uleb128()),
+NumStmt:   int(r.uleb128()),
+})
+}
+r.uleb128()
+if r.err != nil {
+return fmt.Errorf("function %d: %w", fn, r.err)
+}
+m.funcs[covFuncKey{pkg, uint32(fn)}] = covFunc{file: file, units: units}
+}
+return nil
+}
+func covCounterMode(mode byte) string {
+switch mode {
+case 1:
+return "set"
+case 2:
+return "count"
+case 3:
+return "atomic"
+}
+return ""
+}
+func readCovCounterFile(path string, meta *covMetaFile, counters map[covFuncKey][]int) error {
+data, err := os.ReadFile(path)
+if err != nil {
+return err
+}
+if len(data) < covCounterHeaderSize+covCounterFooterSize || !bytes.Equal(data[:4], covCounterMagic) {
+return fmt.Errorf("not a coverage counter data file")
+}
+if !bytes.Equal(data[len(data)-covCounterFooterSize:len(data)-covCounterFooterSize+4], covCounterMagic) {
+return fmt.Errorf("invalid counter data file footer")
+}
+r := &covReader{data: data, off: 4}
+if version := r.uint32(); version != 1 {
+return fmt.Errorf("unsupported counter data file version %d", version)
+}
+if hash := hex.EncodeToString(r.bytes(16)); hash != meta.hash {
+return fmt.Errorf("counter data does not match meta-data hash %s", meta.hash)
+}
+flavor := data[24]
+order := binary.ByteOrder(binary.LittleEndian)
+if data[25] != 0 {
+order = binary.BigEndian
+}
+value := func() int {
+if flavor == covCounterFlavorULeb128 {
+return int(r.uleb128())
+}
+if !r.need(4) {
+return 0
+}
+v := order.Uint32(r.data[r.off:])
+r.off += 4
+return int(v)
+}
+if flavor != covCounterFlavorRaw && flavor != covCounterFlavorULeb128 {
+return fmt.Errorf("unsupported counter flavor %d", flavor)
+}
+footer := &covReader{data: data, off: len(data) - covCounterFooterSize + 8}
+segments := int(footer.uint32())
+r.off = covCounterHeaderSize
+for seg := 0; seg < segments; seg++ {
+if seg > 0 {
+r.off += covCounterFooterSize
+}
+entries := r.uint64()
+strTabLen := int(r.uint32())
+argsLen := int(r.uint32())
+r.bytes(strTabLen + argsLen)
+if rem := r.off % 4; rem != 0 {
+r.off += 4 - rem
+}
+for i := uint64(0); i < entries && r.err == nil; i++ {
+n := value()
+key := covFuncKey{uint32(value()), uint32(value())}
+if n > len(data) {
+return fmt.Errorf("invalid counter count %d", n)
+}
+values := make([]int, n)
+for j := range values {
+values[j] = value()
+}
+if _, ok := meta.funcs[key]; !ok {
+continue
+}
+existing := counters[key]
+if len(existing) < len(values) {
+grown := make([]int, len(values))
+copy(grown, existing)
+existing = grown
+}
+for j, v := range values {
+existing[j] = combineCounts(meta.mode, existing[j], v)
+}
+counters[key] = existing
+}
+if r.err != nil {
+return fmt.Errorf("segment %d: %w", seg, r.err)
+}
+}
+return nil
+}
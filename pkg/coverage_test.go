@@ -1,7 +1,23 @@
 package coverage
 import (
+"archive/tar"
+"archive/zip"
+"compress/gzip"
+"encoding/json"
+"encoding/xml"
+"fmt"
+"io"
+"net/http"
+"net/http/httptest"
 "os"
+"os/exec"
+"path/filepath"
+"reflect"
+"regexp"
+"strings"
 "testing"
+"testing/fstest"
+"time"
 )
 func TestParseCoverageFile(t *testing.T) {
 content := []byte(`mode: atomic
@@ -99,3 +115,1638 @@ if info.Size() == 0 {
 t.Error("Expected non-empty HTML file")
 }
 }
+func writeCoverageFile(t *testing.T, content string) string {
+t.Helper()
+tmpfile, err := os.CreateTemp("", "coverage-*.out")
+if err != nil {
+t.Fatal(err)
+}
+t.Cleanup(func() { os.Remove(tmpfile.Name()) })
+if _, err := tmpfile.WriteString(content); err != nil {
+t.Fatal(err)
+}
+if err := tmpfile.Close(); err != nil {
+t.Fatal(err)
+}
+return tmpfile.Name()
+}
+func TestParseCoverageFiles(t *testing.T) {
+first := writeCoverageFile(t, `mode: count
+example.com/app/a.go:3.10,5.2 2 1
+example.com/app/a.go:7.10,9.2 1 0
+`)
+second := writeCoverageFile(t, `mode: count
+example.com/app/a.go:3.10,5.2 2 4
+example.com/app/a.go:7.10,9.2 1 2
+example.com/app/b.go:1.1,2.2 3 0
+`)
+report, err := ParseCoverageFiles([]string{first, second})
+if err != nil {
+t.Fatalf("Failed to parse coverage files: %v", err)
+}
+if len(report.Files) != 2 {
+t.Fatalf("Expected 2 files, got %d", len(report.Files))
+}
+blocks := report.Files["example.com/app/a.go"].Blocks
+if len(blocks) != 2 {
+t.Fatalf("Expected 2 blocks, got %d", len(blocks))
+}
+if blocks[0].Count != 5 || blocks[1].Count != 2 {
+t.Errorf("Expected summed counts 5 and 2, got %d and %d", blocks[0].Count, blocks[1].Count)
+}
+total, covered, _ := report.GetOverallStats()
+if total != 6 || covered != 3 {
+t.Errorf("Expected 3/6 statements, got %d/%d", covered, total)
+}
+}
+func TestMergeReports(t *testing.T) {
+a := &CoverageReport{
+Mode: "set",
+Files: map[string]*FileCoverage{
+"a.go": {FileName: "a.go", Blocks: []CoverageBlock{{StartLine: 1, EndLine: 2, NumStmt: 1, Count: 1}}},
+},
+}
+b := &CoverageReport{
+Mode: "set",
+Files: map[string]*FileCoverage{
+"a.go": {FileName: "a.go", Blocks: []CoverageBlock{{StartLine: 1, EndLine: 2, NumStmt: 1, Count: 1}}},
+},
+}
+merged, err := MergeReports(a, b)
+if err != nil {
+t.Fatalf("Failed to merge reports: %v", err)
+}
+if count := merged.Files["a.go"].Blocks[0].Count; count != 1 {
+t.Errorf("Expected set mode count 1, got %d", count)
+}
+c := &CoverageReport{Mode: "count", Files: map[string]*FileCoverage{}}
+if _, err := MergeReports(a, c); err == nil {
+t.Error("Expected error when merging set and count modes")
+}
+d := &CoverageReport{Mode: "atomic", Files: map[string]*FileCoverage{}}
+if _, err := MergeReports(c, d); err != nil {
+t.Errorf("Expected count and atomic modes to merge, got %v", err)
+}
+}
+func TestParseCoverageFileDeduplicatesBlocks(t *testing.T) {
+name := writeCoverageFile(t, `mode: count
+example.com/app/a.go:3.10,5.2 2 1
+example.com/app/a.go:7.10,9.2 1 0
+example.com/app/a.go:3.10,5.2 2 0
+example.com/app/a.go:7.10,9.2 1 3
+`)
+report, err := ParseCoverageFile(name)
+if err != nil {
+t.Fatalf("Failed to parse coverage file: %v", err)
+}
+fc := report.Files["example.com/app/a.go"]
+if len(fc.Blocks) != 2 {
+t.Fatalf("Expected 2 blocks, got %d", len(fc.Blocks))
+}
+if fc.Blocks[0].Count != 1 || fc.Blocks[1].Count != 3 {
+t.Errorf("Expected merged counts 1 and 3, got %d and %d", fc.Blocks[0].Count, fc.Blocks[1].Count)
+}
+total, covered, _ := fc.GetCoverageStats()
+if total != 3 || covered != 3 {
+t.Errorf("Expected 3/3 statements, got %d/%d", covered, total)
+}
+}
+func writeSourceFile(t *testing.T, path, content string) {
+t.Helper()
+if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+t.Fatal(err)
+}
+if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+t.Fatal(err)
+}
+}
+func TestModuleResolver(t *testing.T) {
+root := t.TempDir()
+modCache := t.TempDir()
+writeSourceFile(t, filepath.Join(root, "app", "go.mod"), `module example.com/app
+
+go 1.21
+
+require (
+	example.com/lib v1.0.0
+	github.com/Upper/dep v0.2.0 // indirect
+)
+
+replace example.com/lib => ../lib
+`)
+writeSourceFile(t, filepath.Join(root, "app", "internal", "svc.go"), "package internal\n")
+writeSourceFile(t, filepath.Join(root, "lib", "lib.go"), "package lib\n")
+writeSourceFile(t, filepath.Join(root, "app", "vendor", "example.com", "vendored", "v.go"), "package vendored\n")
+writeSourceFile(t, filepath.Join(modCache, "github.com", "!upper", "dep@v0.2.0", "dep.go"), "package dep\n")
+resolver, err := NewModuleResolver(filepath.Join(root, "app"))
+if err != nil {
+t.Fatalf("Failed to create resolver: %v", err)
+}
+resolver.ModCache = modCache
+if resolver.ModulePath != "example.com/app" {
+t.Errorf("Expected module path 'example.com/app', got '%s'", resolver.ModulePath)
+}
+tests := []struct {
+name string
+want string
+}{
+{"example.com/app/internal/svc.go", filepath.Join(root, "app", "internal", "svc.go")},
+{"example.com/lib/lib.go", filepath.Join(root, "lib", "lib.go")},
+{"example.com/vendored/v.go", filepath.Join(root, "app", "vendor", "example.com", "vendored", "v.go")},
+{"github.com/Upper/dep/dep.go", filepath.Join(modCache, "github.com", "!upper", "dep@v0.2.0", "dep.go")},
+}
+for _, tt := range tests {
+got, err := resolver.Resolve(tt.name)
+if err != nil {
+t.Errorf("Failed to resolve %s: %v", tt.name, err)
+continue
+}
+if got != tt.want {
+t.Errorf("For %s, expected %s, got %s", tt.name, tt.want, got)
+}
+}
+if _, err := resolver.Resolve("example.com/app/missing.go"); err == nil {
+t.Error("Expected error for missing source file")
+}
+}
+func readSource(t *testing.T, resolver SourceResolver, name string) string {
+t.Helper()
+rc, err := resolver.Open(name)
+if err != nil {
+t.Fatalf("Failed to open %s: %v", name, err)
+}
+defer rc.Close()
+content, err := io.ReadAll(rc)
+if err != nil {
+t.Fatal(err)
+}
+return string(content)
+}
+func TestFSResolver(t *testing.T) {
+resolver := NewFSResolver(fstest.MapFS{
+"go.mod":        {Data: []byte("module example.com/app\n")},
+"internal/a.go": {Data: []byte("package internal\n")},
+})
+if got := readSource(t, resolver, "example.com/app/internal/a.go"); got != "package internal\n" {
+t.Errorf("Unexpected content %q", got)
+}
+if _, err := resolver.Open("example.com/app/missing.go"); err == nil {
+t.Error("Expected error for missing source file")
+}
+}
+func TestArchiveResolver(t *testing.T) {
+dir := t.TempDir()
+zipPath := filepath.Join(dir, "src.zip")
+zipFile, err := os.Create(zipPath)
+if err != nil {
+t.Fatal(err)
+}
+zw := zip.NewWriter(zipFile)
+for name, content := range map[string]string{
+"repo-main/go.mod":        "module example.com/app\n",
+"repo-main/internal/a.go": "package internal\n",
+} {
+w, err := zw.Create(name)
+if err != nil {
+t.Fatal(err)
+}
+w.Write([]byte(content))
+}
+if err := zw.Close(); err != nil {
+t.Fatal(err)
+}
+zipFile.Close()
+tarPath := filepath.Join(dir, "src.tar.gz")
+tarFile, err := os.Create(tarPath)
+if err != nil {
+t.Fatal(err)
+}
+gz := gzip.NewWriter(tarFile)
+tw := tar.NewWriter(gz)
+for name, content := range map[string]string{
+"./go.mod":        "module example.com/app\n",
+"./internal/a.go": "package internal\n",
+} {
+tw.WriteHeader(&tar.Header{Name: name, Mode: 0644, Size: int64(len(content)), Typeflag: tar.TypeReg})
+tw.Write([]byte(content))
+}
+tw.Close()
+gz.Close()
+tarFile.Close()
+for _, archive := range []string{zipPath, tarPath} {
+resolver, err := NewArchiveResolver(archive)
+if err != nil {
+t.Fatalf("Failed to open archive %s: %v", archive, err)
+}
+if resolver.ModulePath != "example.com/app" {
+t.Errorf("Expected module path 'example.com/app', got '%s'", resolver.ModulePath)
+}
+if got := readSource(t, resolver, "example.com/app/internal/a.go"); got != "package internal\n" {
+t.Errorf("Unexpected content %q from %s", got, archive)
+}
+}
+}
+func TestGitResolver(t *testing.T) {
+if _, err := exec.LookPath("git"); err != nil {
+t.Skip("git not available")
+}
+dir := t.TempDir()
+writeSourceFile(t, filepath.Join(dir, "go.mod"), "module example.com/app\n")
+writeSourceFile(t, filepath.Join(dir, "a.go"), "package app // v1\n")
+git := func(args ...string) {
+cmd := exec.Command("git", append([]string{"-C", dir, "-c", "user.name=test", "-c", "user.email=test@example.com"}, args...)...)
+if out, err := cmd.CombinedOutput(); err != nil {
+t.Fatalf("git %v failed: %v\n%s", args, err, out)
+}
+}
+git("init", "-q")
+git("add", "-A")
+git("commit", "-q", "-m", "v1")
+writeSourceFile(t, filepath.Join(dir, "a.go"), "package app // v2\n")
+resolver, err := NewGitResolver(dir, "HEAD")
+if err != nil {
+t.Fatalf("Failed to create git resolver: %v", err)
+}
+if got := readSource(t, resolver, "example.com/app/a.go"); got != "package app // v1\n" {
+t.Errorf("Expected committed content, got %q", got)
+}
+if _, err := NewGitResolver(dir, "no-such-rev"); err == nil {
+t.Error("Expected error for unknown revision")
+}
+}
+func TestGetFileWithSourceUsesResolver(t *testing.T) {
+resolver := NewFSResolver(fstest.MapFS{
+"pkg/a.go": {Data: []byte("package pkg\n\nfunc A() {\n}\n")},
+})
+fc := &FileCoverage{
+FileName: "pkg/a.go",
+Blocks:   []CoverageBlock{{StartLine: 3, StartCol: 10, EndLine: 4, EndCol: 2, NumStmt: 1, Count: 1}},
+}
+fileWithSource, err := GetFileWithSource(resolver, "pkg/a.go", fc)
+if err != nil {
+t.Fatalf("Failed to load source: %v", err)
+}
+if len(fileWithSource.Lines) != 4 {
+t.Fatalf("Expected 4 lines, got %d", len(fileWithSource.Lines))
+}
+if !fileWithSource.Lines[2].IsCovered {
+t.Error("Expected line 3 to be covered")
+}
+}
+func TestParseCoverageDir(t *testing.T) {
+if testing.Short() {
+t.Skip("skipping go build in short mode")
+}
+goTool, err := exec.LookPath("go")
+if err != nil {
+t.Skip("go tool not available")
+}
+dir := t.TempDir()
+writeSourceFile(t, filepath.Join(dir, "go.mod"), "module example.com/covdemo\n\ngo 1.21\n")
+writeSourceFile(t, filepath.Join(dir, "main.go"), `package main
+
+import "os"
+
+func main() {
+	if len(os.Args) > 1 {
+		println(classify(len(os.Args[1])))
+		return
+	}
+	double := func(n int) int { return n * 2 }
+	println(double(3))
+}
+
+func classify(n int) string {
+	if n > 3 {
+		return "long"
+	}
+	return "short"
+}
+`)
+run := func(env []string, name string, args ...string) {
+cmd := exec.Command(name, args...)
+cmd.Dir = dir
+cmd.Env = append(os.Environ(), env...)
+if out, err := cmd.CombinedOutput(); err != nil {
+t.Fatalf("%s %v failed: %v\n%s", name, args, err, out)
+}
+}
+run(nil, goTool, "build", "-cover", "-covermode=count", "-o", "demo", ".")
+covDir := filepath.Join(dir, "covdata")
+if err := os.Mkdir(covDir, 0755); err != nil {
+t.Fatal(err)
+}
+binary := filepath.Join(dir, "demo")
+run([]string{"GOCOVERDIR=" + covDir}, binary)
+run([]string{"GOCOVERDIR=" + covDir}, binary, "hello")
+run([]string{"GOCOVERDIR=" + covDir}, binary, "hello")
+run(nil, goTool, "tool", "covdata", "textfmt", "-i="+covDir, "-o="+filepath.Join(dir, "ref.out"))
+want, err := ParseCoverageFile(filepath.Join(dir, "ref.out"))
+if err != nil {
+t.Fatalf("Failed to parse textfmt output: %v", err)
+}
+got, err := ParseCoverageDir(covDir)
+if err != nil {
+t.Fatalf("Failed to parse coverage directory: %v", err)
+}
+if got.Mode != want.Mode {
+t.Errorf("Expected mode '%s', got '%s'", want.Mode, got.Mode)
+}
+if !reflect.DeepEqual(got.Files, want.Files) {
+t.Errorf("Coverage data differs from go tool covdata textfmt")
+for name, fc := range got.Files {
+t.Logf("got %s: %+v", name, fc.Blocks)
+}
+for name, fc := range want.Files {
+t.Logf("want %s: %+v", name, fc.Blocks)
+}
+}
+merged, err := ParseCoverageDirs([]string{covDir, covDir})
+if err != nil {
+t.Fatalf("Failed to merge coverage directories: %v", err)
+}
+for name, fc := range merged.Files {
+for i, block := range fc.Blocks {
+if expected := want.Files[name].Blocks[i].Count * 2; block.Count != expected {
+t.Errorf("%s block %d: expected merged count %d, got %d", name, i, expected, block.Count)
+}
+}
+}
+}
+func TestGetFunctionCoverage(t *testing.T) {
+src := []byte(`package demo
+
+type T struct{}
+
+func (t *T) Method() int {
+	return 1
+}
+
+func Outer() func() int {
+	x := 1
+	return func() int {
+		return x
+	}
+}
+`)
+fc := &FileCoverage{
+FileName: "demo.go",
+Blocks: []CoverageBlock{
+{StartLine: 5, StartCol: 26, EndLine: 7, EndCol: 2, NumStmt: 1, Count: 3},
+{StartLine: 9, StartCol: 25, EndLine: 11, EndCol: 19, NumStmt: 2, Count: 1},
+{StartLine: 11, StartCol: 19, EndLine: 13, EndCol: 3, NumStmt: 1, Count: 0},
+},
+}
+funcs, err := GetFunctionCoverage("demo.go", src, fc)
+if err != nil {
+t.Fatalf("Failed to compute function coverage: %v", err)
+}
+expected := []struct {
+name    string
+line    int
+total   int
+covered int
+}{
+{"(*T).Method", 5, 1, 1},
+{"Outer", 9, 2, 2},
+{"Outer.func1", 11, 1, 0},
+}
+if len(funcs) != len(expected) {
+t.Fatalf("Expected %d functions, got %d", len(expected), len(funcs))
+}
+for i, want := range expected {
+got := funcs[i]
+if got.Name != want.name || got.StartLine != want.line || got.Total != want.total || got.Covered != want.covered {
+t.Errorf("Function %d: expected %+v, got %+v", i, want, got)
+}
+}
+}
+func TestPackages(t *testing.T) {
+report := &CoverageReport{
+Mode: "set",
+Files: map[string]*FileCoverage{
+"example.com/app/a/x.go": {FileName: "example.com/app/a/x.go", Blocks: []CoverageBlock{{NumStmt: 4, Count: 1}}},
+"example.com/app/a/y.go": {FileName: "example.com/app/a/y.go", Blocks: []CoverageBlock{{NumStmt: 4, Count: 0}}},
+"example.com/app/b/z.go": {FileName: "example.com/app/b/z.go", Blocks: []CoverageBlock{{NumStmt: 2, Count: 1}}},
+},
+}
+packages := report.Packages()
+if len(packages) != 2 {
+t.Fatalf("Expected 2 packages, got %d", len(packages))
+}
+if packages[0].Path != "example.com/app/a" || packages[1].Path != "example.com/app/b" {
+t.Errorf("Unexpected package paths %s, %s", packages[0].Path, packages[1].Path)
+}
+if len(packages[0].Files) != 2 {
+t.Errorf("Expected 2 files in package a, got %d", len(packages[0].Files))
+}
+total, covered, pct := packages[0].GetCoverageStats()
+if total != 8 || covered != 4 || pct != 50 {
+t.Errorf("Expected 4/8 (50%%), got %d/%d (%.1f%%)", covered, total, pct)
+}
+}
+func TestCheckThresholds(t *testing.T) {
+report := &CoverageReport{
+Mode: "set",
+Files: map[string]*FileCoverage{
+"example.com/app/a/x.go":   {FileName: "example.com/app/a/x.go", Blocks: []CoverageBlock{{NumStmt: 8, Count: 1}, {NumStmt: 2, Count: 0}}},
+"example.com/app/gen/y.go": {FileName: "example.com/app/gen/y.go", Blocks: []CoverageBlock{{NumStmt: 10, Count: 0}}},
+},
+}
+violations := CheckThresholds(report, Thresholds{MinTotal: 50, MinFile: 60, MinPackage: 60})
+if len(violations) != 3 {
+t.Fatalf("Expected 3 violations, got %d: %v", len(violations), violations)
+}
+if violations[0].Scope != "total" || violations[1].Path != "example.com/app/gen" || violations[2].Path != "example.com/app/gen/y.go" {
+t.Errorf("Unexpected violations: %v", violations)
+}
+override, err := ParseThresholdOverride("example.com/app/gen/...=0")
+if err != nil {
+t.Fatalf("Failed to parse override: %v", err)
+}
+violations = CheckThresholds(report, Thresholds{MinFile: 60, MinPackage: 60, Overrides: []ThresholdOverride{override}})
+if len(violations) != 0 {
+t.Errorf("Expected no violations with override, got %v", violations)
+}
+if _, err := ParseThresholdOverride("missing-percent"); err == nil {
+t.Error("Expected error for override without value")
+}
+empty := &CoverageReport{Mode: "set", Files: map[string]*FileCoverage{}}
+violations = CheckThresholds(empty, Thresholds{MinTotal: 90})
+if len(violations) != 1 || !violations[0].Empty || violations[0].String() != "coverage report has no statements, cannot reach minimum 90.0%" {
+t.Errorf("Expected a no statements violation for an empty report, got %v", violations)
+}
+if violations := CheckThresholds(empty, Thresholds{}); len(violations) != 0 {
+t.Errorf("Expected no violations for an empty report without a minimum, got %v", violations)
+}
+}
+func TestParseUnifiedDiff(t *testing.T) {
+diff := `diff --git a/internal/svc.go b/internal/svc.go
+index 1111111..2222222 100644
+--- a/internal/svc.go
++++ b/internal/svc.go
+@@ -3,0 +4,2 @@ func A() {
++	x := 1
++	y := 2
+@@ -10 +12 @@ func B() {
+-	return 0
++	return 1
+diff --git a/old.go b/old.go
+deleted file mode 100644
+--- a/old.go
++++ /dev/null
+@@ -1,2 +0,0 @@
+-package old
+-
+diff --git a/ctx.go b/ctx.go
+--- a/ctx.go
++++ b/ctx.go
+@@ -1,3 +1,4 @@
+ package ctx
++// added
+ 
+ func C() {}
+`
+changes, err := ParseUnifiedDiff(strings.NewReader(diff))
+if err != nil {
+t.Fatalf("Failed to parse diff: %v", err)
+}
+if got := changes["internal/svc.go"]; !reflect.DeepEqual(got, []int{4, 5, 12}) {
+t.Errorf("Expected changed lines [4 5 12], got %v", got)
+}
+if got := changes["ctx.go"]; !reflect.DeepEqual(got, []int{2}) {
+t.Errorf("Expected changed lines [2], got %v", got)
+}
+if _, exists := changes["old.go"]; exists {
+t.Error("Expected deleted file to be ignored")
+}
+report := &CoverageReport{Mode: "set", Files: map[string]*FileCoverage{"example.com/app/internal/svc.go": {FileName: "example.com/app/internal/svc.go"}}}
+if got := changes.Resolve(report, nil, "").Lookup("example.com/app/internal/svc.go"); len(got) != 3 {
+t.Errorf("Expected lookup by import path to find 3 lines, got %v", got)
+}
+}
+func TestResolveChangedLines(t *testing.T) {
+report := &CoverageReport{
+Mode: "set",
+Files: map[string]*FileCoverage{
+"example.com/x/main.go":          {FileName: "example.com/x/main.go"},
+"example.com/x/cmd/tool/main.go": {FileName: "example.com/x/cmd/tool/main.go"},
+"example.com/x/internal/svc.go":  {FileName: "example.com/x/internal/svc.go"},
+},
+}
+module := &FSResolver{ModulePath: "example.com/x"}
+changes := ChangedLines{"main.go": {3}, "internal/svc.go": {7}}
+resolved := changes.Resolve(report, module, "")
+if got := resolved.Lookup("example.com/x/main.go"); !reflect.DeepEqual(got, []int{3}) {
+t.Errorf("Expected root main.go to get line 3, got %v", got)
+}
+if got := resolved.Lookup("example.com/x/cmd/tool/main.go"); got != nil {
+t.Errorf("Expected cmd/tool/main.go to have no changed lines, got %v", got)
+}
+if got := resolved.Lookup("example.com/x/internal/svc.go"); !reflect.DeepEqual(got, []int{7}) {
+t.Errorf("Expected internal/svc.go to get line 7, got %v", got)
+}
+resolved = ChangedLines{"services/x/cmd/tool/main.go": {5}}.Resolve(report, module, "services/x")
+if got := resolved.Lookup("example.com/x/cmd/tool/main.go"); !reflect.DeepEqual(got, []int{5}) {
+t.Errorf("Expected repository-relative path to map into the module, got %v", got)
+}
+if resolved := changes.Resolve(report, nil, ""); len(resolved.Lookup("example.com/x/main.go")) != 0 || len(resolved.Lookup("example.com/x/cmd/tool/main.go")) != 0 {
+t.Errorf("Expected ambiguous suffix match to be skipped, got %v", resolved)
+}
+if got := changes.Resolve(report, nil, "").Lookup("example.com/x/internal/svc.go"); !reflect.DeepEqual(got, []int{7}) {
+t.Errorf("Expected unambiguous suffix match as fallback, got %v", got)
+}
+}
+func TestComputeDiffCoverage(t *testing.T) {
+resolver := NewFSResolver(fstest.MapFS{
+"go.mod": {Data: []byte("module example.com/app\n")},
+"a.go":   {Data: []byte("package app\n\nfunc A(x int) int {\n\tif x > 0 {\n\t\treturn 1\n\t}\n\treturn 0\n}\n")},
+})
+report := &CoverageReport{
+Mode: "set",
+Files: map[string]*FileCoverage{
+"example.com/app/a.go": {FileName: "example.com/app/a.go", Blocks: []CoverageBlock{
+{StartLine: 3, StartCol: 19, EndLine: 4, EndCol: 11, NumStmt: 1, Count: 1},
+{StartLine: 4, StartCol: 11, EndLine: 6, EndCol: 3, NumStmt: 1, Count: 0},
+{StartLine: 7, StartCol: 2, EndLine: 7, EndCol: 10, NumStmt: 1, Count: 1},
+}},
+},
+}
+diff := ComputeDiffCoverage(report, ChangedLines{"a.go": {1, 5, 7}}, resolver)
+if len(diff.Files) != 1 {
+t.Fatalf("Expected 1 file, got %d", len(diff.Files))
+}
+if !reflect.DeepEqual(diff.Files[0].Covered, []int{7}) || !reflect.DeepEqual(diff.Files[0].Uncovered, []int{5}) {
+t.Errorf("Unexpected diff coverage %+v", diff.Files[0])
+}
+total, covered, pct := diff.GetCoverageStats()
+if total != 2 || covered != 1 || pct != 50 {
+t.Errorf("Expected 1/2 (50%%), got %d/%d (%.1f%%)", covered, total, pct)
+}
+}
+func TestFormatLineRanges(t *testing.T) {
+if got := FormatLineRanges([]int{1, 2, 3, 5, 7, 8}); got != "1-3, 5, 7-8" {
+t.Errorf("Expected '1-3, 5, 7-8', got '%s'", got)
+}
+}
+func TestCompareReportsShiftedLines(t *testing.T) {
+base := &CoverageReport{Mode: "set", Files: map[string]*FileCoverage{
+"a.go": {FileName: "a.go", Blocks: []CoverageBlock{{StartLine: 2, StartCol: 10, EndLine: 4, EndCol: 2, NumStmt: 1, Count: 0}}},
+}}
+head := &CoverageReport{Mode: "set", Files: map[string]*FileCoverage{
+"a.go": {FileName: "a.go", Blocks: []CoverageBlock{
+{StartLine: 4, StartCol: 10, EndLine: 6, EndCol: 2, NumStmt: 1, Count: 0},
+{StartLine: 7, StartCol: 10, EndLine: 9, EndCol: 2, NumStmt: 1, Count: 0},
+}},
+}}
+baseSources := NewFSResolver(fstest.MapFS{"a.go": {Data: []byte("package a\nfunc A() {\n\tx()\n}\n")}})
+headSources := NewFSResolver(fstest.MapFS{"a.go": {Data: []byte("package a\n// one\n// two\nfunc A() {\n\tx()\n}\nfunc B() {\n\ty()\n}\n")}})
+c := CompareReports(base, head)
+if !c.Files[0].Unmatched || c.Files[0].NewlyUncovered != nil {
+t.Errorf("Expected shifted file without sources to be unmatched, got %+v", c.Files[0])
+}
+c = CompareReportsWithSources(base, head, baseSources, headSources)
+if c.Files[0].Unmatched || !reflect.DeepEqual(c.Files[0].NewlyUncovered, []int{7, 8, 9}) {
+t.Errorf("Expected only the new function B to be newly uncovered, got %+v", c.Files[0])
+}
+if got := matchLines([]string{"a", "b", "c"}, []string{"x", "a", "c", "y"}); !reflect.DeepEqual(got, []int{0, 1, 3, 0}) {
+t.Errorf("Expected line match [0 1 3 0], got %v", got)
+}
+var out strings.Builder
+if err := WriteComparisonText(&out, CompareReports(base, head)); err != nil {
+t.Fatal(err)
+}
+if !strings.Contains(out.String(), "a.go: sources changed") {
+t.Errorf("Expected unmatched file to be reported, got:\n%s", out.String())
+}
+}
+func TestCompareReports(t *testing.T) {
+base := &CoverageReport{
+Mode: "set",
+Files: map[string]*FileCoverage{
+"example.com/app/a/x.go": {FileName: "example.com/app/a/x.go", Blocks: []CoverageBlock{
+{StartLine: 1, EndLine: 2, NumStmt: 2, Count: 1},
+{StartLine: 3, EndLine: 4, NumStmt: 2, Count: 1},
+}},
+"example.com/app/a/old.go": {FileName: "example.com/app/a/old.go", Blocks: []CoverageBlock{{StartLine: 1, EndLine: 1, NumStmt: 1, Count: 1}}},
+},
+}
+head := &CoverageReport{
+Mode: "set",
+Files: map[string]*FileCoverage{
+"example.com/app/a/x.go": {FileName: "example.com/app/a/x.go", Blocks: []CoverageBlock{
+{StartLine: 1, EndLine: 2, NumStmt: 2, Count: 1},
+{StartLine: 3, EndLine: 4, NumStmt: 2, Count: 0},
+}},
+"example.com/app/b/new.go": {FileName: "example.com/app/b/new.go", Blocks: []CoverageBlock{{StartLine: 1, EndLine: 1, NumStmt: 1, Count: 1}}},
+},
+}
+c := CompareReports(base, head)
+if len(c.Files) != 3 {
+t.Fatalf("Expected 3 files, got %d", len(c.Files))
+}
+statuses := map[string]string{}
+for _, f := range c.Files {
+statuses[f.Path] = f.Status
+}
+if statuses["example.com/app/a/x.go"] != "decreased" || statuses["example.com/app/a/old.go"] != "removed" || statuses["example.com/app/b/new.go"] != "added" {
+t.Errorf("Unexpected file statuses %v", statuses)
+}
+x := c.FilesWithStatus("decreased")[0]
+if x.Delta != -50 {
+t.Errorf("Expected delta -50, got %.1f", x.Delta)
+}
+if !reflect.DeepEqual(x.NewlyUncovered, []int{3, 4}) {
+t.Errorf("Expected newly uncovered lines [3 4], got %v", x.NewlyUncovered)
+}
+if len(c.Packages) != 2 || c.Packages[0].Status != "decreased" || c.Packages[1].Status != "added" {
+t.Errorf("Unexpected package deltas %+v", c.Packages)
+}
+var out strings.Builder
+if err := WriteComparisonText(&out, c); err != nil {
+t.Fatalf("Failed to write comparison: %v", err)
+}
+if !strings.Contains(out.String(), "▼ -50.0%") || !strings.Contains(out.String(), "example.com/app/a/x.go: 3-4") {
+t.Errorf("Unexpected comparison output:\n%s", out.String())
+}
+outputPath := filepath.Join(t.TempDir(), "compare.html")
+if err := GenerateComparisonHTML(c, outputPath); err != nil {
+t.Fatalf("Failed to generate comparison HTML: %v", err)
+}
+html, err := os.ReadFile(outputPath)
+if err != nil {
+t.Fatalf("Failed to read comparison HTML: %v", err)
+}
+if !strings.Contains(string(html), "delta-down") || !strings.Contains(string(html), "status-added") {
+t.Error("Expected HTML comparison to mark decreased and added files")
+}
+}
+const coberturaDTD = `<!ELEMENT coverage (sources?,packages)>
+<!ATTLIST coverage line-rate CDATA #REQUIRED>
+<!ATTLIST coverage branch-rate CDATA #REQUIRED>
+<!ATTLIST coverage lines-covered CDATA #REQUIRED>
+<!ATTLIST coverage lines-valid CDATA #REQUIRED>
+<!ATTLIST coverage branches-covered CDATA #REQUIRED>
+<!ATTLIST coverage branches-valid CDATA #REQUIRED>
+<!ATTLIST coverage complexity CDATA #REQUIRED>
+<!ATTLIST coverage version CDATA #REQUIRED>
+<!ATTLIST coverage timestamp CDATA #REQUIRED>
+<!ELEMENT sources (source*)>
+<!ELEMENT source (#PCDATA)>
+<!ELEMENT packages (package*)>
+<!ELEMENT package (classes)>
+<!ATTLIST package name CDATA #REQUIRED>
+<!ATTLIST package line-rate CDATA #REQUIRED>
+<!ATTLIST package branch-rate CDATA #REQUIRED>
+<!ATTLIST package complexity CDATA #REQUIRED>
+<!ELEMENT classes (class*)>
+<!ELEMENT class (methods,lines)>
+<!ATTLIST class name CDATA #REQUIRED>
+<!ATTLIST class filename CDATA #REQUIRED>
+<!ATTLIST class line-rate CDATA #REQUIRED>
+<!ATTLIST class branch-rate CDATA #REQUIRED>
+<!ATTLIST class complexity CDATA #REQUIRED>
+<!ELEMENT methods (method*)>
+<!ELEMENT method (lines)>
+<!ATTLIST method name CDATA #REQUIRED>
+<!ATTLIST method signature CDATA #REQUIRED>
+<!ATTLIST method line-rate CDATA #REQUIRED>
+<!ATTLIST method branch-rate CDATA #REQUIRED>
+<!ATTLIST method complexity CDATA #REQUIRED>
+<!ELEMENT lines (line*)>
+<!ELEMENT line (conditions*)>
+<!ATTLIST line number CDATA #REQUIRED>
+<!ATTLIST line hits CDATA #REQUIRED>
+<!ELEMENT conditions (condition*)>
+<!ELEMENT condition EMPTY>
+<!ATTLIST condition number CDATA #REQUIRED>
+<!ATTLIST condition type CDATA #REQUIRED>
+<!ATTLIST condition coverage CDATA #REQUIRED>`
+func validateAgainstDTD(t *testing.T, dtd string, data []byte) {
+models := map[string]*regexp.Regexp{}
+for _, m := range regexp.MustCompile(`<!ELEMENT (\S+) (.+)>`).FindAllStringSubmatch(dtd, -1) {
+model := ""
+if m[2] != "EMPTY" && m[2] != "(#PCDATA)" {
+model = strings.ReplaceAll(regexp.MustCompile(`[\w-]+`).ReplaceAllString(m[2], "(?:$0 )"), ",", "")
+}
+models[m[1]] = regexp.MustCompile("^" + model + "$")
+}
+required := map[string][]string{}
+for _, m := range regexp.MustCompile(`<!ATTLIST (\S+) (\S+) CDATA #REQUIRED>`).FindAllStringSubmatch(dtd, -1) {
+required[m[1]] = append(required[m[1]], m[2])
+}
+type element struct {
+name     string
+children string
+}
+stack := []*element{{}}
+decoder := xml.NewDecoder(strings.NewReader(string(data)))
+for {
+tok, err := decoder.Token()
+if err == io.EOF {
+break
+}
+if err != nil {
+t.Fatalf("Invalid XML: %v", err)
+}
+switch tok := tok.(type) {
+case xml.StartElement:
+if _, ok := models[tok.Name.Local]; !ok {
+t.Errorf("Element <%s> is not declared in the DTD", tok.Name.Local)
+}
+attrs := map[string]bool{}
+for _, attr := range tok.Attr {
+attrs[attr.Name.Local] = true
+}
+for _, name := range required[tok.Name.Local] {
+if !attrs[name] {
+t.Errorf("Element <%s> is missing required attribute %s", tok.Name.Local, name)
+}
+}
+stack = append(stack, &element{name: tok.Name.Local})
+case xml.EndElement:
+el := stack[len(stack)-1]
+stack = stack[:len(stack)-1]
+if model, ok := models[el.name]; ok && !model.MatchString(el.children) {
+t.Errorf("Children of <%s> do not match the DTD: %q", el.name, el.children)
+}
+stack[len(stack)-1].children += el.name + " "
+}
+}
+if root := stack[0].children; root != "coverage " {
+t.Errorf("Expected a single <coverage> root element, got %q", root)
+}
+}
+func TestCoberturaReport(t *testing.T) {
+resolver := NewFSResolver(fstest.MapFS{
+"go.mod":   {Data: []byte("module example.com/app\n")},
+"pkg/a.go": {Data: []byte("package pkg\n\nfunc A() {\n\tprintln()\n}\n\nfunc B() {\n\tprintln()\n}\n")},
+})
+report := &CoverageReport{
+Mode: "count",
+Files: map[string]*FileCoverage{
+"example.com/app/pkg/a.go": {FileName: "example.com/app/pkg/a.go", Blocks: []CoverageBlock{
+{StartLine: 3, StartCol: 10, EndLine: 5, EndCol: 2, NumStmt: 1, Count: 4},
+{StartLine: 7, StartCol: 10, EndLine: 9, EndCol: 2, NumStmt: 1, Count: 0},
+}},
+},
+}
+var out strings.Builder
+cobertura := &CoberturaReport{Report: report, Resolver: resolver, Version: "test"}
+if err := cobertura.Render(&out); err != nil {
+t.Fatalf("Failed to render cobertura report: %v", err)
+}
+validateAgainstDTD(t, coberturaDTD, []byte(out.String()))
+var doc coberturaCoverage
+if err := xml.Unmarshal([]byte(out.String()), &doc); err != nil {
+t.Fatalf("Failed to parse cobertura report: %v", err)
+}
+if doc.LinesValid != 6 || doc.LinesCovered != 3 || doc.LineRate != 0.5 {
+t.Errorf("Expected 3/6 lines (0.5), got %d/%d (%v)", doc.LinesCovered, doc.LinesValid, doc.LineRate)
+}
+if len(doc.Packages) != 1 || doc.Packages[0].Name != "example.com/app/pkg" {
+t.Fatalf("Unexpected packages %+v", doc.Packages)
+}
+class := doc.Packages[0].Classes[0]
+if class.Name != "a" || class.FileName != "pkg/a.go" {
+t.Errorf("Expected class a in pkg/a.go, got %s in %s", class.Name, class.FileName)
+}
+if len(class.Lines) != 6 || class.Lines[0].Number != 3 || class.Lines[0].Hits != 4 {
+t.Errorf("Unexpected lines %+v", class.Lines)
+}
+if len(class.Methods) != 2 || class.Methods[0].Name != "A" || class.Methods[0].LineRate != 1 || class.Methods[1].LineRate != 0 {
+t.Errorf("Unexpected methods %+v", class.Methods)
+}
+}
+func TestLCOVReport(t *testing.T) {
+resolver := NewFSResolver(fstest.MapFS{
+"go.mod":   {Data: []byte("module example.com/app\n")},
+"pkg/a.go": {Data: []byte("package pkg\n\nfunc A() {\n\tprintln()\n}\n\nfunc B() {\n\tprintln()\n}\n")},
+})
+report := &CoverageReport{
+Mode: "count",
+Files: map[string]*FileCoverage{
+"example.com/app/pkg/a.go": {FileName: "example.com/app/pkg/a.go", Blocks: []CoverageBlock{
+{StartLine: 3, StartCol: 10, EndLine: 5, EndCol: 2, NumStmt: 1, Count: 4},
+{StartLine: 7, StartCol: 10, EndLine: 9, EndCol: 2, NumStmt: 1, Count: 0},
+}},
+},
+}
+var out strings.Builder
+lcov := &LCOVReport{Report: report, Resolver: resolver}
+if err := lcov.Render(&out); err != nil {
+t.Fatalf("Failed to render LCOV report: %v", err)
+}
+expected := `TN:
+SF:pkg/a.go
+FN:3,A
+FN:7,B
+FNDA:4,A
+FNDA:0,B
+FNF:2
+FNH:1
+DA:3,4
+DA:4,4
+DA:5,4
+DA:7,0
+DA:8,0
+DA:9,0
+LF:6
+LH:3
+end_of_record
+`
+if out.String() != expected {
+t.Errorf("Expected LCOV output:\n%s\ngot:\n%s", expected, out.String())
+}
+}
+func TestJSONReportRoundTrip(t *testing.T) {
+resolver := NewFSResolver(fstest.MapFS{
+"go.mod":   {Data: []byte("module example.com/app\n")},
+"pkg/a.go": {Data: []byte("package pkg\n\nfunc A() {\n\tprintln()\n}\n\nfunc B() {\n\tprintln()\n}\n")},
+})
+report := &CoverageReport{
+Mode: "count",
+Files: map[string]*FileCoverage{
+"example.com/app/pkg/a.go": {FileName: "example.com/app/pkg/a.go", Blocks: []CoverageBlock{
+{StartLine: 3, StartCol: 10, EndLine: 5, EndCol: 2, NumStmt: 1, Count: 4},
+{StartLine: 7, StartCol: 10, EndLine: 9, EndCol: 2, NumStmt: 1, Count: 0},
+}},
+"example.com/app/other/b.go": {FileName: "example.com/app/other/b.go", Blocks: []CoverageBlock{
+{StartLine: 1, StartCol: 1, EndLine: 2, EndCol: 2, NumStmt: 3, Count: 1},
+}},
+},
+}
+jsonGen := &JSONReport{Report: report, Resolver: resolver, Version: "test"}
+doc := jsonGen.Document()
+if doc.SchemaVersion != JSONSchemaVersion || doc.Generator.Version != "test" {
+t.Errorf("Unexpected metadata %d %+v", doc.SchemaVersion, doc.Generator)
+}
+if doc.Summary.Statements != 5 || doc.Summary.Covered != 4 {
+t.Errorf("Expected 4/5 statements, got %d/%d", doc.Summary.Covered, doc.Summary.Statements)
+}
+if len(doc.Packages) != 2 || doc.Packages[1].Path != "example.com/app/pkg" || doc.Packages[1].Coverage != 50 {
+t.Errorf("Unexpected packages %+v", doc.Packages)
+}
+a := doc.Files[1]
+if a.Path != "example.com/app/pkg/a.go" || !a.HasSource || len(a.Functions) != 2 {
+t.Fatalf("Unexpected file %+v", a)
+}
+if len(a.Lines) != 6 || a.Lines[0] != (JSONLine{Line: 3, Count: 4}) {
+t.Errorf("Unexpected lines %+v", a.Lines)
+}
+if b := doc.Files[0]; b.HasSource || len(b.Lines) != 2 {
+t.Errorf("Expected block-derived lines without source, got %+v", b.Lines)
+}
+outputPath := filepath.Join(t.TempDir(), "coverage.json")
+if err := jsonGen.Generate(outputPath); err != nil {
+t.Fatalf("Failed to generate JSON report: %v", err)
+}
+loaded, err := LoadJSONReport(outputPath)
+if err != nil {
+t.Fatalf("Failed to load JSON report: %v", err)
+}
+if !reflect.DeepEqual(loaded, report) {
+t.Errorf("Expected round-tripped report to match, got %+v", loaded)
+}
+if err := os.WriteFile(outputPath, []byte(`{"schemaVersion": 99}`), 0644); err != nil {
+t.Fatal(err)
+}
+if _, err := LoadJSONReport(outputPath); err == nil {
+t.Error("Expected error for unsupported schema version")
+}
+}
+func TestMarkdownReport(t *testing.T) {
+report := &CoverageReport{
+Mode:  "set",
+Files: map[string]*FileCoverage{},
+}
+for i := 0; i < 200; i++ {
+name := fmt.Sprintf("example.com/app/pkg%03d/file.go", i)
+report.Files[name] = &FileCoverage{FileName: name, Blocks: []CoverageBlock{{NumStmt: 4, Count: i % 2}}}
+}
+markdown := &MarkdownReport{Report: report, WorstFiles: 3, Details: true}
+out := markdown.String()
+if !strings.Contains(out, "**Total coverage: 🟠 50.0%** (400/800 statements)") {
+t.Errorf("Expected overall coverage line, got:\n%s", out)
+}
+if !strings.Contains(out, "| `example.com/app/pkg000/file.go` | 🔴 0.0% | 0/4 |") || strings.Contains(out, "pkg006/file.go` |") {
+t.Error("Expected the 3 lowest-covered files to be listed")
+}
+if strings.Count(out, "<details>") != 200 {
+t.Errorf("Expected 200 details sections, got %d", strings.Count(out, "<details>"))
+}
+markdown.MaxLength = 20000
+out = markdown.String()
+if len(out) > 20000 {
+t.Errorf("Expected output within 20000 bytes, got %d", len(out))
+}
+if !strings.Contains(out, "| `example.com/app/pkg199` |") || !strings.Contains(out, "omitted to stay within the comment size limit") {
+t.Error("Expected all packages with details trimmed to fit the limit")
+}
+markdown.MaxLength = 2000
+if out = markdown.String(); len(out) > 2000 || !strings.Contains(out, "more packages not shown") {
+t.Errorf("Expected package table to be truncated, got %d bytes", len(out))
+}
+}
+func TestBadge(t *testing.T) {
+var out strings.Builder
+badge := &Badge{Label: "tests & <docs>", Coverage: 45, Style: "flat-square"}
+if err := badge.Render(&out); err != nil {
+t.Fatalf("Failed to render badge: %v", err)
+}
+svg := out.String()
+if err := xml.Unmarshal([]byte(svg), new(struct{})); err != nil {
+t.Errorf("Expected well-formed SVG: %v", err)
+}
+if !strings.Contains(svg, `fill="`+GetCoverageColor(45)+`"`) {
+t.Error("Expected badge to use the coverage color")
+}
+if !strings.Contains(svg, ">tests &amp; &lt;docs&gt;</text>") || !strings.Contains(svg, ">45.0%</text>") {
+t.Errorf("Expected escaped label and percentage, got %s", svg)
+}
+if strings.Contains(svg, "linearGradient") || !strings.Contains(svg, `rx="0"`) {
+t.Error("Expected flat-square badge without gradient and rounded corners")
+}
+badge.Style = "plastic"
+if err := badge.Render(io.Discard); err == nil {
+t.Error("Expected error for unknown badge style")
+}
+}
+func TestRewritePath(t *testing.T) {
+rewrites := []PathRewrite{}
+for _, value := range []string{"example.com/app=services/app", "example.com/app/internal/gen=generated/"} {
+rewrite, err := ParsePathRewrite(value)
+if err != nil {
+t.Fatalf("Failed to parse rewrite %s: %v", value, err)
+}
+rewrites = append(rewrites, rewrite)
+}
+tests := map[string]string{
+"example.com/app/main.go":           "services/app/main.go",
+"example.com/app/internal/gen/x.go": "generated/x.go",
+"example.com/application/main.go":   "main.go",
+"example.com/other/lib.go":          "example.com/other/lib.go",
+}
+for name, expected := range tests {
+if got := RewritePath(rewrites, "example.com/application", name); got != expected {
+t.Errorf("Expected %s to be rewritten to %s, got %s", name, expected, got)
+}
+}
+if _, err := ParsePathRewrite("no-separator"); err == nil {
+t.Error("Expected error for rewrite without '='")
+}
+}
+func TestSonarReport(t *testing.T) {
+resolver := NewFSResolver(fstest.MapFS{
+"go.mod":   {Data: []byte("module example.com/app\n")},
+"pkg/a.go": {Data: []byte("package pkg\n\nfunc A() {\n\tprintln()\n}\n")},
+})
+report := &CoverageReport{
+Mode: "set",
+Files: map[string]*FileCoverage{
+"example.com/app/pkg/a.go": {FileName: "example.com/app/pkg/a.go", Blocks: []CoverageBlock{
+{StartLine: 3, StartCol: 10, EndLine: 4, EndCol: 12, NumStmt: 1, Count: 1},
+{StartLine: 5, StartCol: 1, EndLine: 5, EndCol: 2, NumStmt: 1, Count: 0},
+}},
+},
+}
+var out strings.Builder
+sonar := &SonarReport{Report: report, Resolver: resolver}
+if err := sonar.Render(&out); err != nil {
+t.Fatalf("Failed to render sonar report: %v", err)
+}
+var doc sonarCoverage
+if err := xml.Unmarshal([]byte(out.String()), &doc); err != nil {
+t.Fatalf("Failed to parse sonar report: %v", err)
+}
+if doc.Version != 1 || len(doc.Files) != 1 || doc.Files[0].Path != "pkg/a.go" {
+t.Fatalf("Unexpected sonar report %+v", doc)
+}
+expected := []sonarLine{{3, true}, {4, true}, {5, false}}
+if !reflect.DeepEqual(doc.Files[0].Lines, expected) {
+t.Errorf("Expected lines %v, got %v", expected, doc.Files[0].Lines)
+}
+}
+func TestTextReport(t *testing.T) {
+report := &CoverageReport{
+Mode: "set",
+Files: map[string]*FileCoverage{
+"example.com/app/a/x.go": {FileName: "example.com/app/a/x.go", Blocks: []CoverageBlock{
+{StartLine: 1, EndLine: 2, NumStmt: 2, Count: 1},
+{StartLine: 4, EndLine: 5, NumStmt: 2, Count: 0},
+}},
+"example.com/app/b/y.go": {FileName: "example.com/app/b/y.go", Blocks: []CoverageBlock{{StartLine: 1, EndLine: 1, NumStmt: 1, Count: 0}}},
+},
+}
+resolver := NewFSResolver(fstest.MapFS{})
+var out strings.Builder
+text := &TextReport{Report: report, Resolver: resolver, ShowUncovered: true, SortBy: "coverage"}
+if err := text.Render(&out); err != nil {
+t.Fatalf("Failed to render text report: %v", err)
+}
+expected := `PACKAGE                 COVERAGE    STATEMENTS
+example.com/app/b           0.0%           0/1
+example.com/app/a          50.0%           2/4
+
+FILE                    COVERAGE    STATEMENTS  UNCOVERED LINES
+example.com/app/b/y.go      0.0%           0/1  1
+example.com/app/a/x.go     50.0%           2/4  4-5
+
+TOTAL                      40.0%           2/5
+`
+if out.String() != expected {
+t.Errorf("Expected text report:\n%s\ngot:\n%s", expected, out.String())
+}
+out.Reset()
+text.Color = true
+if err := text.Render(&out); err != nil {
+t.Fatalf("Failed to render text report: %v", err)
+}
+if !strings.Contains(out.String(), "\x1b[31m    0.0%\x1b[0m") {
+t.Errorf("Expected ANSI-colored percentages, got %q", out.String())
+}
+}
+func TestHighlightGo(t *testing.T) {
+src := "package main\n\n// Greet says hi\nfunc Greet(n int) string {\n\treturn `a\nb` + \"x\" + string(rune(n+1))\n}\n"
+lines := HighlightGo([]byte(src))
+if len(lines) != 7 {
+t.Fatalf("Expected 7 lines, got %d", len(lines))
+}
+for i, line := range strings.Split(strings.TrimSuffix(src, "\n"), "\n") {
+var text strings.Builder
+for _, tok := range lines[i] {
+text.WriteString(tok.Text)
+}
+if text.String() != line {
+t.Errorf("Line %d: expected %q, got %q", i+1, line, text.String())
+}
+}
+classes := map[string]string{}
+for _, line := range lines {
+for _, tok := range line {
+classes[tok.Text] = tok.Class
+}
+}
+expected := map[string]string{
+"package":          "keyword",
+"return":           "keyword",
+"// Greet says hi": "comment",
+"Greet":            "ident",
+"int":              "builtin",
+"`a":               "string",
+"b`":               "string",
+"\"x\"":            "string",
+"1":                "number",
+"(":                "",
+}
+for text, class := range expected {
+if got, ok := classes[text]; !ok || got != class {
+t.Errorf("Expected %q to be classified as %q, got %q", text, class, got)
+}
+}
+}
+func TestHTMLReportHighlighting(t *testing.T) {
+resolver := NewFSResolver(fstest.MapFS{
+"a.go": {Data: []byte("package a\n\nfunc A() string {\n\treturn \"<b>\"\n}\n")},
+})
+report := &CoverageReport{
+Mode: "set",
+Files: map[string]*FileCoverage{
+"a.go": {FileName: "a.go", Blocks: []CoverageBlock{{StartLine: 3, StartCol: 18, EndLine: 5, EndCol: 2, NumStmt: 1, Count: 1}}},
+},
+}
+outputPath := filepath.Join(t.TempDir(), "coverage.html")
+htmlGen := &HTMLReport{Report: report, Resolver: resolver}
+if err := htmlGen.Generate(outputPath); err != nil {
+t.Fatalf("Failed to generate HTML report: %v", err)
+}
+content, err := os.ReadFile(outputPath)
+if err != nil {
+t.Fatal(err)
+}
+html := string(content)
+if !strings.Contains(html, `<span class="tok-keyword">func</span> <span class="tok-ident">A</span>() <span class="tok-builtin">string</span> {`) {
+t.Error("Expected highlighted function signature in HTML report")
+}
+if !strings.Contains(html, `<span class="tok-string">&#34;&lt;b&gt;&#34;</span>`) {
+t.Error("Expected escaped string literal in HTML report")
+}
+}
+func TestGetFileWithSourceSpans(t *testing.T) {
+resolver := NewFSResolver(fstest.MapFS{
+"go.mod": {Data: []byte("module example.com/a\n")},
+"a.go":   {Data: []byte("package a\n\nfunc F(err error) error {\n\tif err != nil { return err }\n\treturn nil\n}\n")},
+})
+report, err := ParseCoverageFile(writeCoverageFile(t, `mode: count
+example.com/a/a.go:4.2,4.16 1 1
+example.com/a/a.go:4.18,4.30 1 0
+example.com/a/a.go:5.2,5.12 1 1
+`))
+if err != nil {
+t.Fatalf("Failed to parse coverage: %v", err)
+}
+fileWithSource, err := GetFileWithSource(resolver, "example.com/a/a.go", report.Files["example.com/a/a.go"])
+if err != nil {
+t.Fatalf("Failed to load source: %v", err)
+}
+line := fileWithSource.Lines[3]
+if !line.Partial {
+t.Error("Expected line 4 to be partially covered")
+}
+type span struct {
+text         string
+count        int
+instrumented bool
+}
+got := []span{}
+for _, s := range line.Spans {
+var text strings.Builder
+for _, tok := range s.Tokens {
+text.WriteString(tok.Text)
+}
+got = append(got, span{text.String(), s.Count, s.Instrumented})
+}
+expected := []span{{"\t", 0, false}, {"if err != nil ", 1, true}, {"{ ", 0, false}, {"return err }", 0, true}}
+if !reflect.DeepEqual(got, expected) {
+t.Errorf("Expected spans %v, got %v", expected, got)
+}
+if line.Tooltip != "block 4.2,4.16: count 1\nblock 4.18,4.30: count 0" {
+t.Errorf("Unexpected tooltip %q", line.Tooltip)
+}
+if fileWithSource.Lines[4].Partial || len(fileWithSource.Lines[4].Spans) != 2 {
+t.Errorf("Expected line 5 to be fully covered with 2 spans, got %+v", fileWithSource.Lines[4].Spans)
+}
+}
+func TestLineAggregationWithSharedBlocks(t *testing.T) {
+src := "package a\n\nfunc F(err error) error {\n\tif err != nil { return err }\n\treturn nil\n}\n\nfunc H(x int) string {\n\tif x > 0 {\n\t\treturn \"pos\"\n\t} else if x < 0 {\n\t\treturn \"neg\"\n\t}\n\treturn \"zero\"\n}\n\nfunc K(xs []int) (n int) {\n\tfor _, x := range xs { if x > 1 { n += x } }\n\tfunc() { n++ }()\n\treturn\n}\n"
+profile := `mode: count
+example.com/a/a.go:4.2,4.16 1 1
+example.com/a/a.go:4.18,4.30 1 0
+example.com/a/a.go:5.2,5.12 1 1
+example.com/a/a.go:9.2,9.11 1 3
+example.com/a/a.go:10.3,11.1 1 2
+example.com/a/a.go:11.9,11.18 1 1
+example.com/a/a.go:12.3,13.1 1 1
+example.com/a/a.go:14.2,14.15 1 0
+example.com/a/a.go:18.2,18.23 1 1
+example.com/a/a.go:18.25,18.34 1 3
+example.com/a/a.go:18.36,18.44 1 2
+example.com/a/a.go:19.2,19.9 1 1
+example.com/a/a.go:19.11,19.16 1 1
+example.com/a/a.go:20.2,20.8 1 1
+`
+report, err := ParseCoverageFile(writeCoverageFile(t, profile))
+if err != nil {
+t.Fatalf("Failed to parse coverage: %v", err)
+}
+resolver := NewFSResolver(fstest.MapFS{
+"go.mod": {Data: []byte("module example.com/a\n")},
+"a.go":   {Data: []byte(src)},
+})
+fileWithSource, err := GetFileWithSource(resolver, "example.com/a/a.go", report.Files["example.com/a/a.go"])
+if err != nil {
+t.Fatalf("Failed to load source: %v", err)
+}
+tests := []struct {
+line         int
+count        int
+blocks       int
+instrumented bool
+partial      bool
+}{
+{4, 1, 2, true, true},
+{5, 1, 1, true, false},
+{9, 3, 1, true, false},
+{10, 2, 1, true, false},
+{11, 1, 1, true, false},
+{13, 0, 0, false, false},
+{14, 0, 1, true, false},
+{18, 3, 3, true, false},
+{19, 1, 2, true, false},
+}
+for _, tt := range tests {
+line := fileWithSource.Lines[tt.line-1]
+if line.Count != tt.count || len(line.Blocks) != tt.blocks || line.Instrumented != tt.instrumented || line.Partial != tt.partial {
+t.Errorf("Line %d: expected count=%d blocks=%d instrumented=%v partial=%v, got count=%d blocks=%d instrumented=%v partial=%v",
+tt.line, tt.count, tt.blocks, tt.instrumented, tt.partial, line.Count, len(line.Blocks), line.Instrumented, line.Partial)
+}
+if line.IsCovered != (tt.count > 0) {
+t.Errorf("Line %d: expected IsCovered=%v", tt.line, tt.count > 0)
+}
+}
+blocks := append([]CoverageBlock{}, report.Files["example.com/a/a.go"].Blocks...)
+for i, j := 0, len(blocks)-1; i < j; i, j = i+1, j-1 {
+blocks[i], blocks[j] = blocks[j], blocks[i]
+}
+reversed := linesFromBlocks(&FileCoverage{FileName: "a.go", Blocks: blocks})
+for i, line := range linesFromBlocks(report.Files["example.com/a/a.go"]) {
+if line.Count != reversed[i].Count || line.Partial != reversed[i].Partial || line.IsCovered != reversed[i].IsCovered {
+t.Errorf("Line %d: aggregate depends on block order", line.LineNumber)
+}
+}
+}
+func TestLineAggregationWithOverlappingBlocks(t *testing.T) {
+lines := linesFromBlocks(&FileCoverage{FileName: "a.go", Blocks: []CoverageBlock{
+{StartLine: 1, StartCol: 5, EndLine: 3, EndCol: 10, NumStmt: 2, Count: 0},
+{StartLine: 2, StartCol: 3, EndLine: 2, EndCol: 20, NumStmt: 1, Count: 5},
+{StartLine: 3, StartCol: 12, EndLine: 4, EndCol: 1, NumStmt: 1, Count: 2},
+}})
+if len(lines) != 4 {
+t.Fatalf("Expected 4 lines, got %d", len(lines))
+}
+if lines[0].Count != 0 || lines[0].IsCovered || lines[0].Partial {
+t.Errorf("Expected line 1 to be uncovered, got %+v", lines[0])
+}
+if lines[1].Count != 5 || !lines[1].IsCovered || !lines[1].Partial {
+t.Errorf("Expected line 2 to be partial with count 5, got %+v", lines[1])
+}
+if lines[2].Count != 2 || !lines[2].Partial || len(lines[2].Blocks) != 2 {
+t.Errorf("Expected line 3 to be partial with count 2, got %+v", lines[2])
+}
+if lines[3].Instrumented {
+t.Error("Expected block ending at column 1 not to instrument line 4")
+}
+}
+func TestFileTree(t *testing.T) {
+files := map[string]*FileCoverage{
+"example.com/app/a/a.go":     {FileName: "example.com/app/a/a.go", Blocks: []CoverageBlock{{StartLine: 1, EndLine: 2, NumStmt: 3, Count: 1}}},
+"example.com/app/a/b/b.go":   {FileName: "example.com/app/a/b/b.go", Blocks: []CoverageBlock{{StartLine: 1, EndLine: 2, NumStmt: 1, Count: 0}}},
+"example.com/app/c/d/e/e.go": {FileName: "example.com/app/c/d/e/e.go", Blocks: []CoverageBlock{{StartLine: 1, EndLine: 2, NumStmt: 4, Count: 0}}},
+}
+tree := BuildFileTree(files)
+if pct := tree.GetCoveragePercentage(); pct != 37.5 {
+t.Errorf("Expected root coverage 37.5, got %v", pct)
+}
+tree = CompactFileTree(tree)
+if len(tree.Children) != 1 || tree.Children[0].Name != "example.com/app" || tree.Children[0].Path != "example.com/app" {
+t.Fatalf("Expected single compacted root directory example.com/app, got %+v", tree.Children)
+}
+app := tree.Children[0]
+names := []string{}
+for _, child := range app.Children {
+names = append(names, child.Name)
+}
+if !reflect.DeepEqual(names, []string{"a", "c/d/e"}) {
+t.Errorf("Expected children [a c/d/e], got %v", names)
+}
+a := app.Children[0]
+if pct := a.GetCoveragePercentage(); pct != 75 {
+t.Errorf("Expected coverage 75 for directory a, got %v", pct)
+}
+if len(a.Children) != 2 || !a.Children[0].IsDir || a.Children[1].Path != "example.com/app/a/a.go" {
+t.Errorf("Expected directories before files in a, got %+v", a.Children)
+}
+output := filepath.Join(t.TempDir(), "coverage.html")
+if err := GenerateHTMLReport(&CoverageReport{Mode: "set", Files: files}, output); err != nil {
+t.Fatalf("Failed to generate HTML report: %v", err)
+}
+data, err := os.ReadFile(output)
+if err != nil {
+t.Fatal(err)
+}
+html := string(data)
+for _, want := range []string{`data-dir="example.com/app/c/d/e"`, `data-file="example.com/app/c/d/e/e.go"`, `<span class="tree-name">c/d/e</span>`, `>75.0%</span>`, `>37.5%</span>`} {
+if !strings.Contains(html, want) {
+t.Errorf("Expected HTML to contain %s", want)
+}
+}
+}
+func TestHTMLSite(t *testing.T) {
+resolver := NewFSResolver(fstest.MapFS{
+"a/a.go":   {Data: []byte("package a\n\nfunc A() int {\n\treturn 1\n}\n")},
+"a/b/b.go": {Data: []byte("package b\n\nfunc B() int {\n\treturn 2\n}\n")},
+})
+report := &CoverageReport{
+Mode: "set",
+Files: map[string]*FileCoverage{
+"a/a.go":   {FileName: "a/a.go", Blocks: []CoverageBlock{{StartLine: 3, StartCol: 14, EndLine: 5, EndCol: 2, NumStmt: 1, Count: 1}}},
+"a/b/b.go": {FileName: "a/b/b.go", Blocks: []CoverageBlock{{StartLine: 3, StartCol: 14, EndLine: 5, EndCol: 2, NumStmt: 1, Count: 0}}},
+"../x.go":  {FileName: "../x.go", Blocks: []CoverageBlock{{StartLine: 1, EndLine: 1, NumStmt: 1, Count: 0}}},
+},
+}
+dir := t.TempDir()
+site := &HTMLSite{Report: report, Resolver: resolver}
+if err := site.Generate(dir); err != nil {
+t.Fatalf("Failed to generate HTML site: %v", err)
+}
+pages := map[string][]string{
+"index.html":              {`href="assets/style.css"`, `href="packages/a/b/index.html"`, `href="files/a/a.go.html"`, `src="assets/report.js"`},
+"packages/a/b/index.html": {`href="../../../index.html"`, `href="../../../files/a/b/b.go.html"`, `src="../../../assets/report.js"`},
+"files/a/b/b.go.html":     {`href="../../../index.html"`, `href="../../../packages/a/b/index.html"`, `class="line-uncovered"`, `<span class="tok-keyword">return</span>`},
+"files/_/x.go.html":       {`href="../../index.html"`, "Source file not found"},
+"assets/style.css":        {".line-covered"},
+"assets/report.js":        {"function toggleDir("},
+}
+for page, wants := range pages {
+data, err := os.ReadFile(filepath.Join(dir, filepath.FromSlash(page)))
+if err != nil {
+t.Errorf("Expected page %s: %v", page, err)
+continue
+}
+for _, want := range wants {
+if !strings.Contains(string(data), want) {
+t.Errorf("Expected %s to contain %s", page, want)
+}
+}
+}
+index, err := os.ReadFile(filepath.Join(dir, "index.html"))
+if err != nil {
+t.Fatal(err)
+}
+if strings.Contains(string(index), "tok-keyword") {
+t.Error("Expected index page without inlined source")
+}
+site.Changes = ChangedLines{"a/a.go": {4}, "a/b/b.go": {4}}
+if err := site.Generate(dir); err != nil {
+t.Fatalf("Failed to generate HTML site: %v", err)
+}
+if index, err = os.ReadFile(filepath.Join(dir, "index.html")); err != nil {
+t.Fatal(err)
+}
+if !strings.Contains(string(index), "1 / 2 lines") {
+t.Error("Expected index page with patch coverage of the changed lines")
+}
+}
+func TestServer(t *testing.T) {
+resolver := NewFSResolver(fstest.MapFS{
+"a/a.go": {Data: []byte("package a\n\nfunc A() int {\n\treturn 1\n}\n")},
+})
+profile := writeCoverageFile(t, "mode: set\na/a.go:3.14,5.2 1 0\n")
+server, err := NewServer(func() (*CoverageReport, error) { return ParseCoverageFile(profile) }, resolver, []string{profile})
+if err != nil {
+t.Fatalf("Failed to create server: %v", err)
+}
+get := func(path string) (int, string) {
+rec := httptest.NewRecorder()
+server.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, path, nil))
+return rec.Code, rec.Body.String()
+}
+for path, want := range map[string]string{
+"/":                      `href="files/a/a.go.html"`,
+"/packages/a/index.html": `href="../../files/a/a.go.html"`,
+"/files/a/a.go.html":     `<span class="tok-keyword">return</span>`,
+"/assets/report.js":      "function toggleDir(",
+} {
+code, body := get(path)
+if code != http.StatusOK || !strings.Contains(body, want) {
+t.Errorf("Expected %s to return 200 containing %s, got %d", path, want, code)
+}
+}
+if code, _ := get("/files/a/missing.go.html"); code != http.StatusNotFound {
+t.Errorf("Expected 404 for unknown file page, got %d", code)
+}
+if code, _ := get("/api/files/a/missing.go"); code != http.StatusNotFound {
+t.Errorf("Expected 404 for unknown API file, got %d", code)
+}
+var file JSONFile
+if code, body := get("/api/files/a/a.go"); code != http.StatusOK || json.Unmarshal([]byte(body), &file) != nil {
+t.Fatalf("Expected JSON file response, got %d: %s", code, body)
+}
+if !file.HasSource || len(file.Functions) != 1 || file.Functions[0].Name != "A" || len(file.Blocks) != 1 {
+t.Errorf("Expected file with source, function A and one block, got %+v", file)
+}
+if err := os.WriteFile(profile, []byte("mode: set\na/a.go:3.14,5.2 1 1\n"), 0o644); err != nil {
+t.Fatal(err)
+}
+future := time.Now().Add(time.Hour)
+if err := os.Chtimes(profile, future, future); err != nil {
+t.Fatal(err)
+}
+var summary ServerSummary
+if code, body := get("/api/summary"); code != http.StatusOK || json.Unmarshal([]byte(body), &summary) != nil {
+t.Fatalf("Expected JSON summary response, got %d: %s", code, body)
+}
+if summary.Summary.Coverage != 100 || len(summary.Files) != 1 || summary.Files[0].Package != "a" {
+t.Errorf("Expected reloaded summary with 100%% coverage, got %+v", summary)
+}
+}
+func TestServerLoadError(t *testing.T) {
+profile := writeCoverageFile(t, "mode: set\na/a.go:3.14,5.2 1 1\n")
+loads := 0
+var loadErr error
+server, err := NewServer(func() (*CoverageReport, error) {
+loads++
+if loadErr != nil {
+return nil, loadErr
+}
+return ParseCoverageFile(profile)
+}, NewFSResolver(fstest.MapFS{}), []string{profile})
+if err != nil {
+t.Fatalf("Failed to create server: %v", err)
+}
+loadErr = fmt.Errorf("malformed profile")
+future := time.Now().Add(time.Hour)
+if err := os.Chtimes(profile, future, future); err != nil {
+t.Fatal(err)
+}
+for i := 0; i < 3; i++ {
+rec := httptest.NewRecorder()
+server.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/api/summary", nil))
+var summary ServerSummary
+if err := json.Unmarshal(rec.Body.Bytes(), &summary); err != nil {
+t.Fatalf("Expected JSON summary response, got %s", rec.Body.String())
+}
+if summary.Error != "malformed profile" || summary.Summary.Coverage != 100 {
+t.Errorf("Expected previous report with the load error, got %+v", summary)
+}
+}
+if loads != 2 {
+t.Errorf("Expected the failed load to run once until the file changes again, got %d loads", loads)
+}
+loadErr = nil
+future = future.Add(time.Hour)
+if err := os.Chtimes(profile, future, future); err != nil {
+t.Fatal(err)
+}
+rec := httptest.NewRecorder()
+server.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/api/summary", nil))
+if strings.Contains(rec.Body.String(), `"error"`) {
+t.Errorf("Expected the error to clear after a successful reload, got %s", rec.Body.String())
+}
+}
+func TestChangedPackages(t *testing.T) {
+root := t.TempDir()
+write := func(name, content string) {
+t.Helper()
+path := filepath.Join(root, filepath.FromSlash(name))
+if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+t.Fatal(err)
+}
+if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+t.Fatal(err)
+}
+}
+for _, name := range []string{"main.go", "p/p.go", "q/q1.go", "q/q2.go", "r/r.go", "s/s.go", "vendor/v/v.go", "testdata/t.go", "tools/go.mod", "tools/gen/gen.go"} {
+write(name, "package x\n")
+}
+before, err := ScanGoFiles(root)
+if err != nil {
+t.Fatalf("Failed to scan: %v", err)
+}
+if len(before) != 6 {
+t.Errorf("Expected 6 files outside vendor, testdata and nested modules, got %d", len(before))
+}
+write("main.go", "package main\n\nfunc main() {}\n")
+write("p/p.go", "package p\n\nfunc P() {}\n")
+write("vendor/v/v.go", "package v\n\nfunc V() {}\n")
+write("tools/gen/gen.go", "package gen\n\nfunc Gen() {}\n")
+for _, name := range []string{"q/q1.go", "r/r.go"} {
+if err := os.Remove(filepath.Join(root, filepath.FromSlash(name))); err != nil {
+t.Fatal(err)
+}
+}
+after, err := ScanGoFiles(root)
+if err != nil {
+t.Fatalf("Failed to scan: %v", err)
+}
+packages, removed := ChangedPackages(root, before, after)
+if !reflect.DeepEqual(packages, []string{".", "./p", "./q"}) {
+t.Errorf("Expected changed packages [. ./p ./q], got %v", packages)
+}
+if !reflect.DeepEqual(removed, []string{"./r"}) {
+t.Errorf("Expected removed packages [./r], got %v", removed)
+}
+if packages, removed := ChangedPackages(root, after, after); len(packages) != 0 || len(removed) != 0 {
+t.Errorf("Expected no changes, got %v and %v", packages, removed)
+}
+for pkg, want := range map[string]string{".": "example.com/app", "./r": "example.com/app/r", "./a/b": "example.com/app/a/b"} {
+if got := PackageImportPath("example.com/app", pkg); got != want {
+t.Errorf("Expected import path %s for %s, got %s", want, pkg, got)
+}
+}
+if got := PackageImportPath("", "./r"); got != "r" {
+t.Errorf("Expected import path r without a module path, got %s", got)
+}
+}
+func TestOverlayReport(t *testing.T) {
+base := &CoverageReport{
+Mode: "set",
+Files: map[string]*FileCoverage{
+"example.com/app/a/a.go":   {FileName: "example.com/app/a/a.go", Blocks: []CoverageBlock{{StartLine: 1, EndLine: 2, NumStmt: 1, Count: 0}}},
+"example.com/app/a/old.go": {FileName: "example.com/app/a/old.go", Blocks: []CoverageBlock{{StartLine: 1, EndLine: 2, NumStmt: 1, Count: 0}}},
+"example.com/app/b/b.go":   {FileName: "example.com/app/b/b.go", Blocks: []CoverageBlock{{StartLine: 1, EndLine: 2, NumStmt: 1, Count: 1}}},
+},
+}
+overlay := &CoverageReport{
+Mode: "set",
+Files: map[string]*FileCoverage{
+"example.com/app/a/a.go": {FileName: "example.com/app/a/a.go", Blocks: []CoverageBlock{{StartLine: 1, EndLine: 2, NumStmt: 1, Count: 1}}},
+},
+}
+result, err := OverlayReport(base, overlay)
+if err != nil {
+t.Fatalf("Failed to overlay reports: %v", err)
+}
+if len(result.Files) != 2 {
+t.Errorf("Expected 2 files after replacing package a, got %d", len(result.Files))
+}
+if result.Files["example.com/app/a/a.go"].Blocks[0].Count != 1 {
+t.Error("Expected package a to be replaced by the overlay")
+}
+if result.Files["example.com/app/b/b.go"] != base.Files["example.com/app/b/b.go"] {
+t.Error("Expected package b to be kept from the base report")
+}
+result, err = OverlayReport(base, overlay, "example.com/app/b")
+if err != nil {
+t.Fatalf("Failed to overlay reports: %v", err)
+}
+if len(result.Files) != 1 || result.Files["example.com/app/b/b.go"] != nil {
+t.Errorf("Expected removed package b to be dropped, got %d files", len(result.Files))
+}
+result, err = OverlayReport(base, &CoverageReport{Mode: "set", Files: map[string]*FileCoverage{}}, "example.com/app/a")
+if err != nil {
+t.Fatalf("Failed to overlay reports: %v", err)
+}
+if len(result.Files) != 1 || result.Files["example.com/app/b/b.go"] == nil {
+t.Errorf("Expected only package b after removing package a, got %d files", len(result.Files))
+}
+if _, err := OverlayReport(base, &CoverageReport{Mode: "count", Files: map[string]*FileCoverage{}}); err == nil {
+t.Error("Expected error for incompatible modes")
+}
+}
+func TestServerEvents(t *testing.T) {
+report := &CoverageReport{Mode: "set", Files: map[string]*FileCoverage{}}
+server, err := NewServer(func() (*CoverageReport, error) { return report, nil }, NewFSResolver(fstest.MapFS{}), nil)
+if err != nil {
+t.Fatalf("Failed to create server: %v", err)
+}
+ts := httptest.NewServer(server)
+defer ts.Close()
+index, err := http.Get(ts.URL + "/")
+if err != nil {
+t.Fatal(err)
+}
+body, _ := io.ReadAll(index.Body)
+index.Body.Close()
+if !strings.Contains(string(body), "new EventSource(") {
+t.Error("Expected served pages to subscribe to reload events")
+}
+resp, err := http.Get(ts.URL + "/events")
+if err != nil {
+t.Fatal(err)
+}
+defer resp.Body.Close()
+if ct := resp.Header.Get("Content-Type"); ct != "text/event-stream" {
+t.Errorf("Expected text/event-stream, got %s", ct)
+}
+buf := make([]byte, 256)
+if _, err := resp.Body.Read(buf); err != nil {
+t.Fatal(err)
+}
+events := make(chan string, 1)
+go func() {
+var received strings.Builder
+for !strings.Contains(received.String(), "event: reload") {
+n, err := resp.Body.Read(buf)
+if err != nil {
+break
+}
+received.Write(buf[:n])
+}
+events <- received.String()
+}()
+server.SetReport(&CoverageReport{Mode: "set", Files: map[string]*FileCoverage{
+"a/a.go": {FileName: "a/a.go", Blocks: []CoverageBlock{{StartLine: 1, EndLine: 2, NumStmt: 1, Count: 1}}},
+}})
+select {
+case received := <-events:
+if !strings.Contains(received, "event: reload") {
+t.Errorf("Expected reload event, got %q", received)
+}
+case <-time.After(5 * time.Second):
+t.Fatal("Timed out waiting for reload event")
+}
+if got := len(server.Report().Files); got != 1 {
+t.Errorf("Expected updated report with 1 file, got %d", got)
+}
+}
+func TestWriteCoverageProfile(t *testing.T) {
+report := &CoverageReport{
+Mode: "count",
+Files: map[string]*FileCoverage{
+"example.com/app/b.go": {FileName: "example.com/app/b.go", Blocks: []CoverageBlock{{StartLine: 3, StartCol: 14, EndLine: 5, EndCol: 2, NumStmt: 1, Count: 0}}},
+"example.com/app/a.go": {FileName: "example.com/app/a.go", Blocks: []CoverageBlock{
+{StartLine: 3, StartCol: 20, EndLine: 4, EndCol: 12, NumStmt: 1, Count: 7},
+{StartLine: 7, StartCol: 2, EndLine: 7, EndCol: 10, NumStmt: 2, Count: 0},
+}},
+},
+}
+var b strings.Builder
+if err := WriteCoverageProfile(&b, report); err != nil {
+t.Fatalf("Failed to write coverage profile: %v", err)
+}
+expected := "mode: count\n" +
+"example.com/app/a.go:3.20,4.12 1 7\n" +
+"example.com/app/a.go:7.2,7.10 2 0\n" +
+"example.com/app/b.go:3.14,5.2 1 0\n"
+if b.String() != expected {
+t.Errorf("Expected profile:\n%s\ngot:\n%s", expected, b.String())
+}
+parsed, err := ParseCoverageFile(writeCoverageFile(t, b.String()))
+if err != nil {
+t.Fatalf("Failed to parse written profile: %v", err)
+}
+if !reflect.DeepEqual(parsed, report) {
+t.Errorf("Expected round trip to return the same report, got %+v", parsed)
+}
+}
@@ -0,0 +1,226 @@
+package coverage
+import (
+"bufio"
+"bytes"
+"fmt"
+"io"
+"sort"
+"strconv"
+"strings"
+)
+type ChangedLines map[string][]int
+type FileDiffCoverage struct {
+Path      string
+Covered   []int
+Uncovered []int
+}
+type DiffCoverage struct {
+Files []FileDiffCoverage
+}
+func ParseUnifiedDiff(r io.Reader) (ChangedLines, error) {
+changes := make(ChangedLines)
+scanner := bufio.NewScanner(r)
+scanner.Buffer(make([]byte, 0, 64*1024), 16*1024*1024)
+current := ""
+oldRemaining, newRemaining, newLine := 0, 0, 0
+for scanner.Scan() {
+line := scanner.Text()
+if oldRemaining > 0 || newRemaining > 0 {
+switch {
+case strings.HasPrefix(line, "+"):
+if current != "" {
+changes[current] = append(changes[current], newLine)
+}
+newLine++
+newRemaining--
+case strings.HasPrefix(line, "-"):
+oldRemaining--
+case strings.HasPrefix(line, "\\"):
+default:
+newLine++
+newRemaining--
+oldRemaining--
+}
+continue
+}
+switch {
+case strings.HasPrefix(line, "+++ "):
+current = diffFileName(strings.TrimPrefix(line, "+++ "))
+case strings.HasPrefix(line, "@@ "):
+var err error
+oldRemaining, newLine, newRemaining, err = parseHunkHeader(line)
+if err != nil {
+return nil, err
+}
+}
+}
+if err := scanner.Err(); err != nil {
+return nil, fmt.Errorf("error reading diff: %w", err)
+}
+for name, lines := range changes {
+sort.Ints(lines)
+changes[name] = lines
+}
+return changes, nil
+}
+func diffFileName(name string) string {
+if i := strings.Index(name, "\t"); i >= 0 {
+name = name[:i]
+}
+if name == "/dev/null" {
+return ""
+}
+if unquoted, err := strconv.Unquote(name); err == nil {
+name = unquoted
+}
+return strings.TrimPrefix(name, "b/")
+}
+func parseHunkHeader(line string) (oldCount, newStart, newCount int, err error) {
+fields := strings.Fields(line)
+if len(fields) < 3 || !strings.HasPrefix(fields[1], "-") || !strings.HasPrefix(fields[2], "+") {
+return 0, 0, 0, fmt.Errorf("invalid hunk header %q", line)
+}
+_, oldCount, err = parseHunkRange(fields[1][1:])
+if err != nil {
+return 0, 0, 0, fmt.Errorf("invalid hunk header %q: %w", line, err)
+}
+newStart, newCount, err = parseHunkRange(fields[2][1:])
+if err != nil {
+return 0, 0, 0, fmt.Errorf("invalid hunk header %q: %w", line, err)
+}
+return oldCount, newStart, newCount, nil
+}
+func parseHunkRange(value string) (start, count int, err error) {
+count = 1
+if i := strings.Index(value, ","); i >= 0 {
+if count, err = strconv.Atoi(value[i+1:]); err != nil {
+return 0, 0, err
+}
+value = value[:i]
+}
+start, err = strconv.Atoi(value)
+return start, count, err
+}
+func GitDiff(dir, base string) (ChangedLines, error) {
+out, err := runGit(dir, "diff", "--no-color", "--no-ext-diff", "--relative", "-U0", base+"...HEAD")
+if err != nil {
+return nil, err
+}
+return ParseUnifiedDiff(bytes.NewReader(out))
+}
+func GitPrefix(dir string) string {
+out, err := runGit(dir, "rev-parse", "--show-prefix")
+if err != nil {
+return ""
+}
+return strings.TrimSuffix(strings.TrimSpace(string(out)), "/")
+}
+func (c ChangedLines) Lookup(profilePath string) []int {
+return c[profilePath]
+}
+func (c ChangedLines) Resolve(report *CoverageReport, resolver SourceResolver, moduleDir string) ChangedLines {
+if c == nil {
+return nil
+}
+modulePath := sourceModulePath(resolver)
+resolved := make(ChangedLines)
+for name, lines := range c {
+profilePath, ok := resolveDiffPath(report, name, modulePath, moduleDir)
+if !ok {
+continue
+}
+resolved[profilePath] = append(resolved[profilePath], lines...)
+}
+for name, lines := range resolved {
+sort.Ints(lines)
+resolved[name] = lines
+}
+return resolved
+}
+func resolveDiffPath(report *CoverageReport, diffPath, modulePath, moduleDir string) (string, bool) {
+if diffPath == "" {
+return "", false
+}
+if _, ok := report.Files[diffPath]; ok {
+return diffPath, true
+}
+rel := diffPath
+if moduleDir != "" && strings.HasPrefix(rel, moduleDir+"/") {
+rel = strings.TrimPrefix(rel, moduleDir+"/")
+}
+if modulePath != "" {
+if _, ok := report.Files[modulePath+"/"+rel]; ok {
+return modulePath + "/" + rel, true
+}
+}
+match := ""
+for profilePath := range report.Files {
+if strings.HasSuffix(profilePath, "/"+rel) {
+if match != "" {
+return "", false
+}
+match = profilePath
+}
+}
+return match, match != ""
+}
+func ComputeDiffCoverage(report *CoverageReport, changes ChangedLines, resolver SourceResolver) *DiffCoverage {
+if resolver == nil {
+resolver = defaultSourceResolver()
+}
+paths := make([]string, 0, len(report.Files))
+for p := range report.Files {
+paths = append(paths, p)
+}
+sort.Strings(paths)
+changes = changes.Resolve(report, resolver, "")
+diff := &DiffCoverage{Files: []FileDiffCoverage{}}
+for _, p := range paths {
+changed := changes.Lookup(p)
+if len(changed) == 0 {
+continue
+}
+lines := fileLines(resolver, p, report.Files[p])
+fileDiff := FileDiffCoverage{Path: p}
+for _, n := range changed {
+if n < 1 || n > len(lines) || !lines[n-1].Instrumented {
+continue
+}
+if lines[n-1].IsCovered {
+fileDiff.Covered = append(fileDiff.Covered, n)
+} else {
+fileDiff.Uncovered = append(fileDiff.Uncovered, n)
+}
+}
+if len(fileDiff.Covered)+len(fileDiff.Uncovered) > 0 {
+diff.Files = append(diff.Files, fileDiff)
+}
+}
+return diff
+}
+func fileLines(resolver SourceResolver, path string, coverage *FileCoverage) []LineCoverage {
+fileWithSource, err := GetFileWithSource(resolver, path, coverage)
+if err != nil || len(fileWithSource.Lines) == 0 {
+return linesFromBlocks(coverage)
+}
+return fileWithSource.Lines
+}
+func (f FileDiffCoverage) GetCoverageStats() (totalLines, coveredLines int, percentage float64) {
+coveredLines = len(f.Covered)
+totalLines = coveredLines + len(f.Uncovered)
+if totalLines > 0 {
+percentage = float64(coveredLines) / float64(totalLines) * 100
+}
+return
+}
+func (d *DiffCoverage) GetCoverageStats() (totalLines, coveredLines int, percentage float64) {
+for _, file := range d.Files {
+total, covered, _ := file.GetCoverageStats()
+totalLines += total
+coveredLines += covered
+}
+if totalLines > 0 {
+percentage = float64(coveredLines) / float64(totalLines) * 100
+}
+return
+}
@@ -0,0 +1,140 @@
+package coverage
+import (
+"fmt"
+"go/ast"
+"go/parser"
+"go/token"
+"sort"
+)
+type FunctionCoverage struct {
+Name      string
+FileName  string
+StartLine int
+StartCol  int
+EndLine   int
+EndCol    int
+Total     int
+Covered   int
+Coverage  float64
+IsLiteral bool
+}
+func GetFunctionCoverage(fileName string, src []byte, coverage *FileCoverage) ([]FunctionCoverage, error) {
+fset := token.NewFileSet()
+file, err := parser.ParseFile(fset, fileName, src, 0)
+if err != nil {
+return nil, fmt.Errorf("failed to parse %s: %w", fileName, err)
+}
+funcs := []FunctionCoverage{}
+stack := []int{}
+literals := map[int]int{}
+ast.Inspect(file, func(n ast.Node) bool {
+if n == nil {
+if len(stack) > 0 {
+stack = stack[:len(stack)-1]
+}
+return true
+}
+var name string
+isLiteral := false
+switch fn := n.(type) {
+case *ast.FuncDecl:
+if fn.Body == nil {
+stack = append(stack, -1)
+return true
+}
+name = funcDeclName(fn)
+case *ast.FuncLit:
+isLiteral = true
+parent := -1
+for i := len(stack) - 1; i >= 0; i-- {
+if stack[i] >= 0 {
+parent = stack[i]
+break
+}
+}
+literals[parent]++
+if parent >= 0 {
+name = funcs[parent].Name
+if funcs[parent].IsLiteral {
+name = fmt.Sprintf("%s.%d", name, literals[parent])
+} else {
+name = fmt.Sprintf("%s.func%d", name, literals[parent])
+}
+} else {
+name = fmt.Sprintf("glob.func%d", literals[parent])
+}
+default:
+stack = append(stack, -1)
+return true
+}
+start := fset.Position(n.Pos())
+end := fset.Position(n.End())
+stack = append(stack, len(funcs))
+funcs = append(funcs, FunctionCoverage{
+Name:      name,
+FileName:  fileName,
+StartLine: start.Line,
+StartCol:  start.Column,
+EndLine:   end.Line,
+EndCol:    end.Column,
+IsLiteral: isLiteral,
+})
+return true
+})
+for _, block := range coverage.Blocks {
+owner := -1
+for i, fn := range funcs {
+if positionBefore(block.StartLine, block.StartCol, fn.StartLine, fn.StartCol) ||
+positionBefore(fn.EndLine, fn.EndCol, block.StartLine, block.StartCol) {
+continue
+}
+if owner < 0 || positionBefore(funcs[owner].StartLine, funcs[owner].StartCol, fn.StartLine, fn.StartCol) {
+owner = i
+}
+}
+if owner < 0 {
+continue
+}
+funcs[owner].Total += block.NumStmt
+if block.Count > 0 {
+funcs[owner].Covered += block.NumStmt
+}
+}
+for i := range funcs {
+if funcs[i].Total > 0 {
+funcs[i].Coverage = float64(funcs[i].Covered) / float64(funcs[i].Total) * 100
+}
+}
+sort.SliceStable(funcs, func(i, j int) bool {
+return positionBefore(funcs[i].StartLine, funcs[i].StartCol, funcs[j].StartLine, funcs[j].StartCol)
+})
+return funcs, nil
+}
+func funcDeclName(fn *ast.FuncDecl) string {
+if fn.Recv == nil || len(fn.Recv.List) == 0 {
+return fn.Name.Name
+}
+recv := fn.Recv.List[0].Type
+pointer := false
+if star, ok := recv.(*ast.StarExpr); ok {
+pointer = true
+recv = star.X
+}
+switch t := recv.(type) {
+case *ast.IndexExpr:
+recv = t.X
+case *ast.IndexListExpr:
+recv = t.X
+}
+typeName := "?"
+if ident, ok := recv.(*ast.Ident); ok {
+typeName = ident.Name
+}
+if pointer {
+return fmt.Sprintf("(*%s).%s", typeName, fn.Name.Name)
+}
+return fmt.Sprintf("%s.%s", typeName, fn.Name.Name)
+}
+func positionBefore(line1, col1, line2, col2 int) bool {
+return line1 < line2 || (line1 == line2 && col1 < col2)
+}
@@ -0,0 +1,92 @@
+package coverage
+import (
+"go/scanner"
+"go/token"
+"strings"
+)
+var goPredeclared = map[string]bool{
+"any": true, "bool": true, "byte": true, "comparable": true, "complex64": true, "complex128": true,
+"error": true, "float32": true, "float64": true, "int": true, "int8": true, "int16": true,
+"int32": true, "int64": true, "rune": true, "string": true, "uint": true, "uint8": true,
+"uint16": true, "uint32": true, "uint64": true, "uintptr": true, "true": true, "false": true,
+"iota": true, "nil": true, "append": true, "cap": true, "clear": true, "close": true,
+"complex": true, "copy": true, "delete": true, "imag": true, "len": true, "make": true,
+"max": true, "min": true, "new": true, "panic": true, "print": true, "println": true,
+"real": true, "recover": true,
+}
+type SourceToken struct {
+Text  string
+Class string
+}
+func HighlightGo(src []byte) [][]SourceToken {
+lines := [][]SourceToken{nil}
+emit := func(text, class string) {
+for i, part := range strings.Split(text, "\n") {
+if i > 0 {
+lines = append(lines, nil)
+}
+last := len(lines) - 1
+if i < strings.Count(text, "\n") {
+part = strings.TrimSuffix(part, "\r")
+}
+if part == "" {
+continue
+}
+if n := len(lines[last]); n > 0 && lines[last][n-1].Class == class {
+lines[last][n-1].Text += part
+continue
+}
+lines[last] = append(lines[last], SourceToken{Text: part, Class: class})
+}
+}
+fset := token.NewFileSet()
+file := fset.AddFile("", fset.Base(), len(src))
+var s scanner.Scanner
+s.Init(file, src, func(token.Position, string) {}, scanner.ScanComments)
+offset := 0
+for {
+pos, tok, lit := s.Scan()
+if tok == token.EOF {
+break
+}
+if tok == token.SEMICOLON && lit == "\n" {
+continue
+}
+start := file.Offset(pos)
+text := lit
+if text == "" {
+text = tok.String()
+}
+end := min(start+len(text), len(src))
+if start < offset {
+continue
+}
+emit(string(src[offset:start]), "")
+class := tokenClass(tok)
+if tok == token.IDENT && goPredeclared[lit] {
+class = "builtin"
+}
+emit(string(src[start:end]), class)
+offset = end
+}
+emit(string(src[offset:]), "")
+if len(lines) > 1 && lines[len(lines)-1] == nil && strings.HasSuffix(string(src), "\n") {
+lines = lines[:len(lines)-1]
+}
+return lines
+}
+func tokenClass(tok token.Token) string {
+switch {
+case tok.IsKeyword():
+return "keyword"
+case tok == token.COMMENT:
+return "comment"
+case tok == token.STRING || tok == token.CHAR:
+return "string"
+case tok == token.INT || tok == token.FLOAT || tok == token.IMAG:
+return "number"
+case tok == token.IDENT:
+return "ident"
+}
+return ""
+}
@@ -7,17 +7,28 @@ import (
 "sort"
 )
 type HTMLReport struct {
-Report *CoverageReport
+Report   *CoverageReport
+Resolver SourceResolver
+Changes  ChangedLines
 }
 type FileInfo struct {
-Path       string
-Name       string
-Coverage   float64
-Total      int
-Covered    int
-Color      string
-Lines      []LineCoverage
-HasSource  bool
+Path      string
+Name      string
+Coverage  float64
+Total     int
+Covered   int
+Color     string
+Lines     []LineCoverage
+Functions []FunctionCoverage
+HasSource bool
+}
+type PackageInfo struct {
+Path     string
+Coverage float64
+Total    int
+Covered  int
+Color    string
+Files    []FileInfo
 }
 func GenerateHTMLReport(report *CoverageReport, outputPath string) error {
 htmlGen := &HTMLReport{Report: report}
@@ -29,13 +40,97 @@ if err != nil {
 return fmt.Errorf("failed to create output file: %w", err)
 }
 defer file.Close()
-tree := BuildFileTree(h.Report.Files)
-totalStmts, coveredStmts, overallPct := h.Report.GetOverallStats()
+resolver := h.Resolver
+if resolver == nil {
+resolver = defaultSourceResolver()
+}
+fileInfos := collectFileInfos(h.Report, resolver, h.Changes)
+data := htmlReportData(h.Report, fileInfos, h.Changes != nil)
+tmpl, err := parseHTMLTemplate("coverage", getHTMLTemplate())
+if err != nil {
+return err
+}
+if err := tmpl.Execute(file, data); err != nil {
+return fmt.Errorf("failed to execute template: %w", err)
+}
+return nil
+}
+func htmlReportData(report *CoverageReport, fileInfos []FileInfo, hasPatch bool) map[string]interface{} {
+totalStmts, coveredStmts, overallPct := report.GetOverallStats()
+patchTotal, patchCovered := 0, 0
+for _, info := range fileInfos {
+total, covered := info.patchStats()
+patchTotal += total
+patchCovered += covered
+}
+data := map[string]interface{}{
+"Mode":         report.Mode,
+"TotalStmts":   totalStmts,
+"CoveredStmts": coveredStmts,
+"OverallPct":   overallPct,
+"OverallColor": GetCoverageColor(overallPct),
+"Files":        fileInfos,
+"Packages":     collectPackageInfos(report, fileInfos),
+"FileTree":     CompactFileTree(BuildFileTree(report.Files)),
+"HasPatch":     hasPatch,
+}
+setPatchStats(data, patchTotal, patchCovered)
+return data
+}
+func (info FileInfo) patchStats() (total, covered int) {
+for _, line := range info.Lines {
+if line.Changed && line.Instrumented {
+total++
+if line.IsCovered {
+covered++
+}
+}
+}
+return total, covered
+}
+func setPatchStats(data map[string]interface{}, total, covered int) {
+pct := 0.0
+if total > 0 {
+pct = float64(covered) / float64(total) * 100
+}
+data["PatchTotal"] = total
+data["PatchCovered"] = covered
+data["PatchPct"] = pct
+data["PatchColor"] = GetCoverageColor(pct)
+}
+func collectFileInfos(report *CoverageReport, resolver SourceResolver, changes ChangedLines) []FileInfo {
+changes = changes.Resolve(report, resolver, "")
 fileInfos := []FileInfo{}
-for path, coverage := range h.Report.Files {
+for path := range report.Files {
+fileInfos = append(fileInfos, loadFileInfo(report, resolver, changes, path))
+}
+sortFileInfos(fileInfos)
+return fileInfos
+}
+func collectFileStats(report *CoverageReport) []FileInfo {
+fileInfos := []FileInfo{}
+for path, coverage := range report.Files {
+fileInfos = append(fileInfos, newFileInfo(path, coverage, &FileWithSource{FileName: path}))
+}
+sortFileInfos(fileInfos)
+return fileInfos
+}
+func loadFileInfo(report *CoverageReport, resolver SourceResolver, changes ChangedLines, path string) FileInfo {
+coverage := report.Files[path]
+fileWithSource, err := GetFileWithSource(resolver, path, coverage)
+if err != nil {
+fileWithSource = &FileWithSource{FileName: path}
+}
+for _, n := range changes.Lookup(path) {
+if n >= 1 && n <= len(fileWithSource.Lines) {
+fileWithSource.Lines[n-1].Changed = true
+}
+}
+return newFileInfo(path, coverage, fileWithSource)
+}
+func newFileInfo(path string, coverage *FileCoverage, fileWithSource *FileWithSource) FileInfo {
 total, covered, pct := coverage.GetCoverageStats()
-fileWithSource, _ := GetFileWithSource(path, coverage)
-fileInfos = append(fileInfos, FileInfo{
+return FileInfo{
 Path:      path,
 Name:      filepath.Base(path),
 Coverage:  pct,
@@ -43,33 +138,61 @@ Total:     total,
 Covered:   covered,
 Color:     GetCoverageColor(pct),
 Lines:     fileWithSource.Lines,
+Functions: fileWithSource.Functions,
 HasSource: len(fileWithSource.Lines) > 0,
-})
 }
+}
+func sortFileInfos(fileInfos []FileInfo) {
 sort.Slice(fileInfos, func(i, j int) bool {
 return fileInfos[i].Path < fileInfos[j].Path
 })
-data := map[string]interface{}{
-"Mode":         h.Report.Mode,
-"TotalStmts":   totalStmts,
-"CoveredStmts": coveredStmts,
-"OverallPct":   overallPct,
-"OverallColor": GetCoverageColor(overallPct),
-"Files":        fileInfos,
-"FileTree":     tree,
 }
-tmpl, err := template.New("coverage").Funcs(template.FuncMap{
-"formatPct": FormatPercentage,
-"getCoverageColor": GetCoverageColor,
-}).Parse(getHTMLTemplate())
-if err != nil {
-return fmt.Errorf("failed to parse template: %w", err)
+func collectPackageInfos(report *CoverageReport, fileInfos []FileInfo) []PackageInfo {
+filesByPath := make(map[string]FileInfo, len(fileInfos))
+for _, info := range fileInfos {
+filesByPath[info.Path] = info
 }
-if err := tmpl.Execute(file, data); err != nil {
-return fmt.Errorf("failed to execute template: %w", err)
+packageInfos := []PackageInfo{}
+for _, pkg := range report.Packages() {
+total, covered, pct := pkg.GetCoverageStats()
+info := PackageInfo{
+Path:     pkg.Path,
+Coverage: pct,
+Total:    total,
+Covered:  covered,
+Color:    GetCoverageColor(pct),
 }
-return nil
+for _, fc := range pkg.Files {
+info.Files = append(info.Files, filesByPath[fc.FileName])
+}
+packageInfos = append(packageInfos, info)
+}
+return packageInfos
 }
 func getHTMLTemplate() string {
 return htmlTemplateContent
 }
+func htmlFuncMap() template.FuncMap {
+return template.FuncMap{
+"formatPct":        FormatPercentage,
+"getCoverageColor": GetCoverageColor,
+"formatDelta":      FormatDelta,
+"deltaArrow":       DeltaArrow,
+"formatLines":      FormatLineRanges,
+"packagePage":      sitePackagePage,
+"filePage":         siteFilePage,
+}
+}
+func parseHTMLTemplate(name, content string) (*template.Template, error) {
+tmpl, err := template.New(name).Funcs(htmlFuncMap()).Parse(htmlStylesTemplate)
+if err != nil {
+return nil, fmt.Errorf("failed to parse template: %w", err)
+}
+if _, err := tmpl.Parse(htmlPartialsTemplate); err != nil {
+return nil, fmt.Errorf("failed to parse template: %w", err)
+}
+if _, err := tmpl.Parse(content); err != nil {
+return nil, fmt.Errorf("failed to parse template: %w", err)
+}
+return tmpl, nil
+}
@@ -0,0 +1,194 @@
+package coverage
+import (
+"encoding/json"
+"fmt"
+"io"
+"os"
+"time"
+)
+const JSONSchemaVersion = 1
+type JSONReport struct {
+Report   *CoverageReport
+Resolver SourceResolver
+Version  string
+}
+type JSONDocument struct {
+SchemaVersion int           `json:"schemaVersion"`
+Generator     JSONGenerator `json:"generator"`
+Mode          string        `json:"mode"`
+Summary       JSONSummary   `json:"summary"`
+Packages      []JSONPackage `json:"packages"`
+Files         []JSONFile    `json:"files"`
+}
+type JSONGenerator struct {
+Name        string    `json:"name"`
+Version     string    `json:"version,omitempty"`
+GeneratedAt time.Time `json:"generatedAt"`
+}
+type JSONSummary struct {
+Statements int     `json:"statements"`
+Covered    int     `json:"covered"`
+Coverage   float64 `json:"coverage"`
+}
+type JSONPackage struct {
+Path string `json:"path"`
+JSONSummary
+Files []string `json:"files"`
+}
+type JSONFile struct {
+Path    string `json:"path"`
+Package string `json:"package"`
+JSONSummary
+HasSource bool           `json:"hasSource"`
+Functions []JSONFunction `json:"functions"`
+Lines     []JSONLine     `json:"lines"`
+Blocks    []JSONBlock    `json:"blocks"`
+}
+type JSONFunction struct {
+Name      string `json:"name"`
+StartLine int    `json:"startLine"`
+EndLine   int    `json:"endLine"`
+JSONSummary
+}
+type JSONLine struct {
+Line  int `json:"line"`
+Count int `json:"count"`
+}
+type JSONBlock struct {
+StartLine  int `json:"startLine"`
+StartCol   int `json:"startCol"`
+EndLine    int `json:"endLine"`
+EndCol     int `json:"endCol"`
+Statements int `json:"statements"`
+Count      int `json:"count"`
+}
+func GenerateJSONReport(report *CoverageReport, outputPath string) error {
+jsonGen := &JSONReport{Report: report}
+return jsonGen.Generate(outputPath)
+}
+func (j *JSONReport) Generate(outputPath string) error {
+file, err := os.Create(outputPath)
+if err != nil {
+return fmt.Errorf("failed to create output file: %w", err)
+}
+defer file.Close()
+return j.Render(file)
+}
+func (j *JSONReport) Render(w io.Writer) error {
+enc := json.NewEncoder(w)
+enc.SetIndent("", "  ")
+if err := enc.Encode(j.Document()); err != nil {
+return fmt.Errorf("failed to encode JSON report: %w", err)
+}
+return nil
+}
+func (j *JSONReport) Document() *JSONDocument {
+resolver := j.Resolver
+if resolver == nil {
+resolver = defaultSourceResolver()
+}
+totalStmts, coveredStmts, overallPct := j.Report.GetOverallStats()
+doc := &JSONDocument{
+SchemaVersion: JSONSchemaVersion,
+Generator: JSONGenerator{
+Name:        "go-coverage",
+Version:     j.Version,
+GeneratedAt: time.Now().UTC().Truncate(time.Second),
+},
+Mode:     j.Report.Mode,
+Summary:  JSONSummary{Statements: totalStmts, Covered: coveredStmts, Coverage: overallPct},
+Packages: []JSONPackage{},
+Files:    []JSONFile{},
+}
+fileInfos := collectFileInfos(j.Report, resolver, nil)
+for _, pkg := range collectPackageInfos(j.Report, fileInfos) {
+doc.Packages = append(doc.Packages, jsonPackage(pkg))
+}
+for _, info := range fileInfos {
+doc.Files = append(doc.Files, jsonFile(j.Report.Files[info.Path], info))
+}
+return doc
+}
+func jsonPackage(pkg PackageInfo) JSONPackage {
+jp := JSONPackage{
+Path:        pkg.Path,
+JSONSummary: JSONSummary{Statements: pkg.Total, Covered: pkg.Covered, Coverage: pkg.Coverage},
+Files:       []string{},
+}
+for _, info := range pkg.Files {
+jp.Files = append(jp.Files, info.Path)
+}
+return jp
+}
+func jsonFile(fc *FileCoverage, info FileInfo) JSONFile {
+jf := JSONFile{
+Path:        info.Path,
+Package:     PackagePath(info.Path),
+JSONSummary: JSONSummary{Statements: info.Total, Covered: info.Covered, Coverage: info.Coverage},
+HasSource:   info.HasSource,
+Functions:   []JSONFunction{},
+Lines:       []JSONLine{},
+Blocks:      []JSONBlock{},
+}
+for _, fn := range info.Functions {
+jf.Functions = append(jf.Functions, JSONFunction{
+Name:        fn.Name,
+StartLine:   fn.StartLine,
+EndLine:     fn.EndLine,
+JSONSummary: JSONSummary{Statements: fn.Total, Covered: fn.Covered, Coverage: fn.Coverage},
+})
+}
+lines := info.Lines
+if !info.HasSource {
+lines = linesFromBlocks(fc)
+}
+for _, line := range lines {
+if line.Instrumented {
+jf.Lines = append(jf.Lines, JSONLine{Line: line.LineNumber, Count: line.Count})
+}
+}
+for _, block := range fc.Blocks {
+jf.Blocks = append(jf.Blocks, JSONBlock{
+StartLine:  block.StartLine,
+StartCol:   block.StartCol,
+EndLine:    block.EndLine,
+EndCol:     block.EndCol,
+Statements: block.NumStmt,
+Count:      block.Count,
+})
+}
+return jf
+}
+func LoadJSONReport(path string) (*CoverageReport, error) {
+file, err := os.Open(path)
+if err != nil {
+return nil, fmt.Errorf("failed to open JSON report: %w", err)
+}
+defer file.Close()
+var doc JSONDocument
+if err := json.NewDecoder(file).Decode(&doc); err != nil {
+return nil, fmt.Errorf("failed to decode JSON report: %w", err)
+}
+if doc.SchemaVersion < 1 || doc.SchemaVersion > JSONSchemaVersion {
+return nil, fmt.Errorf("unsupported JSON report schema version %d", doc.SchemaVersion)
+}
+report := &CoverageReport{
+Mode:  doc.Mode,
+Files: make(map[string]*FileCoverage),
+}
+for _, jf := range doc.Files {
+fc := &FileCoverage{FileName: jf.Path, Blocks: []CoverageBlock{}}
+for _, block := range jf.Blocks {
+fc.Blocks = append(fc.Blocks, CoverageBlock{
+StartLine: block.StartLine,
+StartCol:  block.StartCol,
+EndLine:   block.EndLine,
+EndCol:    block.EndCol,
+NumStmt:   block.Statements,
+Count:     block.Count,
+})
+}
+report.Files[jf.Path] = fc
+}
+return report, nil
+}
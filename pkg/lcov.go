@@ -0,0 +1,100 @@
+package coverage
+import (
+"bufio"
+"fmt"
+"io"
+"os"
+"path/filepath"
+"sort"
+)
+type LCOVReport struct {
+Report   *CoverageReport
+Resolver SourceResolver
+}
+func GenerateLCOVReport(report *CoverageReport, outputPath string) error {
+lcov := &LCOVReport{Report: report}
+return lcov.Generate(outputPath)
+}
+func (l *LCOVReport) Generate(outputPath string) error {
+file, err := os.Create(outputPath)
+if err != nil {
+return fmt.Errorf("failed to create output file: %w", err)
+}
+defer file.Close()
+return l.Render(file)
+}
+func (l *LCOVReport) Render(w io.Writer) error {
+resolver := l.Resolver
+if resolver == nil {
+resolver = defaultSourceResolver()
+}
+paths := make([]string, 0, len(l.Report.Files))
+for p := range l.Report.Files {
+paths = append(paths, p)
+}
+sort.Strings(paths)
+bw := bufio.NewWriter(w)
+for _, p := range paths {
+coverage := l.Report.Files[p]
+lines := linesFromBlocks(coverage)
+var functions []FunctionCoverage
+if fileWithSource, err := GetFileWithSource(resolver, p, coverage); err == nil && len(fileWithSource.Lines) > 0 {
+lines = fileWithSource.Lines
+functions = fileWithSource.Functions
+}
+fmt.Fprintf(bw, "TN:\n")
+fmt.Fprintf(bw, "SF:%s\n", lcovSourcePath(resolver, p))
+for _, fn := range functions {
+fmt.Fprintf(bw, "FN:%d,%s\n", fn.StartLine, fn.Name)
+}
+hitFuncs := 0
+for _, fn := range functions {
+count := functionEntryCount(lines, fn)
+if count > 0 {
+hitFuncs++
+}
+fmt.Fprintf(bw, "FNDA:%d,%s\n", count, fn.Name)
+}
+if len(functions) > 0 {
+fmt.Fprintf(bw, "FNF:%d\n", len(functions))
+fmt.Fprintf(bw, "FNH:%d\n", hitFuncs)
+}
+found, hit := 0, 0
+for _, line := range lines {
+if !line.Instrumented {
+continue
+}
+fmt.Fprintf(bw, "DA:%d,%d\n", line.LineNumber, line.Count)
+found++
+if line.IsCovered {
+hit++
+}
+}
+fmt.Fprintf(bw, "LF:%d\n", found)
+fmt.Fprintf(bw, "LH:%d\n", hit)
+fmt.Fprintf(bw, "end_of_record\n")
+}
+return bw.Flush()
+}
+func functionEntryCount(lines []LineCoverage, fn FunctionCoverage) int {
+for n := fn.StartLine; n <= fn.EndLine && n <= len(lines); n++ {
+if n >= 1 && lines[n-1].Instrumented {
+return lines[n-1].Count
+}
+}
+return 0
+}
+func lcovSourcePath(resolver SourceResolver, name string) string {
+if r, ok := resolver.(*ModuleResolver); ok {
+if resolved, err := r.Resolve(name); err == nil {
+if abs, err := filepath.Abs(resolved); err == nil {
+return abs
+}
+return resolved
+}
+}
+if rel, ok := trimModulePrefix(name, sourceModulePath(resolver)); ok {
+return rel
+}
+return name
+}
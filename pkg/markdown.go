@@ -0,0 +1,126 @@
+package coverage
+import (
+"fmt"
+"io"
+"os"
+"path"
+"sort"
+"strings"
+)
+const GitHubCommentLimit = 65536
+type MarkdownReport struct {
+Report     *CoverageReport
+WorstFiles int
+Details    bool
+MaxLength  int
+}
+func GenerateMarkdownReport(report *CoverageReport, outputPath string) error {
+markdown := &MarkdownReport{Report: report, WorstFiles: 10}
+return markdown.Generate(outputPath)
+}
+func (m *MarkdownReport) Generate(outputPath string) error {
+file, err := os.Create(outputPath)
+if err != nil {
+return fmt.Errorf("failed to create output file: %w", err)
+}
+defer file.Close()
+return m.Render(file)
+}
+func (m *MarkdownReport) Render(w io.Writer) error {
+_, err := io.WriteString(w, m.String())
+return err
+}
+func (m *MarkdownReport) String() string {
+limit := m.MaxLength
+if limit <= 0 {
+limit = GitHubCommentLimit
+}
+var head strings.Builder
+totalStmts, coveredStmts, overallPct := m.Report.GetOverallStats()
+fmt.Fprintf(&head, "## 📊 Coverage Report\n\n")
+fmt.Fprintf(&head, "**Total coverage: %s %s** (%d/%d statements) · mode: `%s`\n", coverageEmoji(overallPct), FormatPercentage(overallPct), coveredStmts, totalStmts, m.Report.Mode)
+if worst := m.worstFiles(); len(worst) > 0 {
+fmt.Fprintf(&head, "\n### Lowest coverage files\n\n")
+fmt.Fprintf(&head, "| File | Coverage | Statements |\n|---|---:|---:|\n")
+for _, fc := range worst {
+head.WriteString(markdownRow(fc.FileName, fc.GetCoverageStats))
+}
+}
+packages := m.Report.Packages()
+fmt.Fprintf(&head, "\n### Packages\n\n")
+fmt.Fprintf(&head, "| Package | Coverage | Statements |\n|---|---:|---:|\n")
+out := head.String()
+for i, pkg := range packages {
+row := markdownRow(packageLabel(pkg.Path), pkg.GetCoverageStats)
+note := fmt.Sprintf("\n_%d more packages not shown._\n", len(packages)-i)
+if len(out)+len(row)+len(note) > limit {
+return out + note
+}
+out += row
+}
+if !m.Details {
+return out
+}
+for i, pkg := range packages {
+section := markdownDetails(pkg)
+note := fmt.Sprintf("\n_Details for %d packages omitted to stay within the comment size limit._\n", len(packages)-i)
+if len(out)+len(section)+len(note) > limit {
+return out + note
+}
+out += section
+}
+return out
+}
+func (m *MarkdownReport) worstFiles() []*FileCoverage {
+files := []*FileCoverage{}
+for _, fc := range m.Report.Files {
+if total, _, _ := fc.GetCoverageStats(); total > 0 {
+files = append(files, fc)
+}
+}
+sort.Slice(files, func(i, j int) bool {
+_, _, pi := files[i].GetCoverageStats()
+_, _, pj := files[j].GetCoverageStats()
+if pi != pj {
+return pi < pj
+}
+return files[i].FileName < files[j].FileName
+})
+if n := max(m.WorstFiles, 0); n < len(files) {
+files = files[:n]
+}
+return files
+}
+func markdownDetails(pkg *PackageCoverage) string {
+var b strings.Builder
+_, _, pct := pkg.GetCoverageStats()
+fmt.Fprintf(&b, "\n<details>\n<summary><code>%s</code> %s %s (%d files)</summary>\n\n", packageLabel(pkg.Path), coverageEmoji(pct), FormatPercentage(pct), len(pkg.Files))
+fmt.Fprintf(&b, "| File | Coverage | Statements |\n|---|---:|---:|\n")
+for _, fc := range pkg.Files {
+b.WriteString(markdownRow(path.Base(fc.FileName), fc.GetCoverageStats))
+}
+fmt.Fprintf(&b, "\n</details>\n")
+return b.String()
+}
+func markdownRow(name string, stats func() (int, int, float64)) string {
+total, covered, pct := stats()
+return fmt.Sprintf("| `%s` | %s %s | %d/%d |\n", strings.ReplaceAll(name, "|", "\\|"), coverageEmoji(pct), FormatPercentage(pct), covered, total)
+}
+func packageLabel(pkgPath string) string {
+if pkgPath == "" {
+return "."
+}
+return pkgPath
+}
+func coverageEmoji(pct float64) string {
+switch {
+case pct >= 80:
+return "🟢"
+case pct >= 60:
+return "🟡"
+case pct >= 40:
+return "🟠"
+default:
+return "🔴"
+}
+}
@@ -0,0 +1,135 @@
+package coverage
+import (
+"fmt"
+"sort"
+)
+type blockKey struct {
+startLine, startCol, endLine, endCol int
+}
+func (b CoverageBlock) key() blockKey {
+return blockKey{b.StartLine, b.StartCol, b.EndLine, b.EndCol}
+}
+func ParseCoverageFiles(filenames []string) (*CoverageReport, error) {
+if len(filenames) == 0 {
+return nil, fmt.Errorf("no coverage files given")
+}
+reports := make([]*CoverageReport, 0, len(filenames))
+for _, name := range filenames {
+report, err := ParseCoverageFile(name)
+if err != nil {
+return nil, fmt.Errorf("%s: %w", name, err)
+}
+reports = append(reports, report)
+}
+return MergeReports(reports...)
+}
+func MergeReports(reports ...*CoverageReport) (*CoverageReport, error) {
+merged := &CoverageReport{
+Files: make(map[string]*FileCoverage),
+}
+for _, report := range reports {
+if report == nil {
+continue
+}
+mode, err := mergeModes(merged.Mode, report.Mode)
+if err != nil {
+return nil, err
+}
+merged.Mode = mode
+}
+for _, report := range reports {
+if report == nil {
+continue
+}
+for name, fc := range report.Files {
+var existing []CoverageBlock
+if prev, ok := merged.Files[name]; ok {
+existing = prev.Blocks
+}
+blocks, err := mergeBlocks(merged.Mode, existing, fc.Blocks)
+if err != nil {
+return nil, fmt.Errorf("%s: %w", name, err)
+}
+merged.Files[name] = &FileCoverage{
+FileName: name,
+Blocks:   blocks,
+}
+}
+}
+return merged, nil
+}
+func mergeModes(a, b string) (string, error) {
+switch {
+case a == "" || a == b:
+return b, nil
+case b == "":
+return a, nil
+case isCountingMode(a) && isCountingMode(b):
+return a, nil
+}
+return "", fmt.Errorf("incompatible coverage modes %q and %q", a, b)
+}
+func isCountingMode(mode string) bool {
+return mode == "count" || mode == "atomic"
+}
+func mergeBlocks(mode string, lists ...[]CoverageBlock) ([]CoverageBlock, error) {
+index := make(map[blockKey]int)
+merged := []CoverageBlock{}
+for _, blocks := range lists {
+for _, block := range blocks {
+i, exists := index[block.key()]
+if !exists {
+index[block.key()] = len(merged)
+merged = append(merged, block)
+continue
+}
+if merged[i].NumStmt != block.NumStmt {
+return nil, fmt.Errorf("inconsistent statement count for block %d.%d,%d.%d: %d != %d",
+block.StartLine, block.StartCol, block.EndLine, block.EndCol, merged[i].NumStmt, block.NumStmt)
+}
+merged[i].Count = combineCounts(mode, merged[i].Count, block.Count)
+}
+}
+sort.SliceStable(merged, func(i, j int) bool {
+if merged[i].StartLine != merged[j].StartLine {
+return merged[i].StartLine < merged[j].StartLine
+}
+return merged[i].StartCol < merged[j].StartCol
+})
+return merged, nil
+}
+func combineCounts(mode string, a, b int) int {
+if mode == "set" {
+if a > 0 || b > 0 {
+return 1
+}
+return 0
+}
+return a + b
+}
+func OverlayReport(base, overlay *CoverageReport, removed ...string) (*CoverageReport, error) {
+mode, err := mergeModes(base.Mode, overlay.Mode)
+if err != nil {
+return nil, err
+}
+replaced := make(map[string]bool)
+for _, pkg := range removed {
+replaced[pkg] = true
+}
+for name := range overlay.Files {
+replaced[PackagePath(name)] = true
+}
+result := &CoverageReport{
+Mode:  mode,
+Files: make(map[string]*FileCoverage, len(base.Files)),
+}
+for name, fc := range base.Files {
+if !replaced[PackagePath(name)] {
+result.Files[name] = fc
+}
+}
+for name, fc := range overlay.Files {
+result.Files[name] = fc
+}
+return result, nil
+}
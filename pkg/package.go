@@ -0,0 +1,50 @@
+package coverage
+import (
+"path"
+"sort"
+)
+type PackageCoverage struct {
+Path  string
+Files []*FileCoverage
+}
+func PackagePath(fileName string) string {
+dir := path.Dir(fileName)
+if dir == "." {
+return ""
+}
+return dir
+}
+func (r *CoverageReport) Packages() []*PackageCoverage {
+byPath := make(map[string]*PackageCoverage)
+for name, fc := range r.Files {
+pkgPath := PackagePath(name)
+pkg, exists := byPath[pkgPath]
+if !exists {
+pkg = &PackageCoverage{Path: pkgPath}
+byPath[pkgPath] = pkg
+}
+pkg.Files = append(pkg.Files, fc)
+}
+packages := make([]*PackageCoverage, 0, len(byPath))
+for _, pkg := range byPath {
+sort.Slice(pkg.Files, func(i, j int) bool {
+return pkg.Files[i].FileName < pkg.Files[j].FileName
+})
+packages = append(packages, pkg)
+}
+sort.Slice(packages, func(i, j int) bool {
+return packages[i].Path < packages[j].Path
+})
+return packages
+}
+func (p *PackageCoverage) GetCoverageStats() (totalStmts, coveredStmts int, percentage float64) {
+for _, file := range p.Files {
+total, covered, _ := file.GetCoverageStats()
+totalStmts += total
+coveredStmts += covered
+}
+if totalStmts > 0 {
+percentage = float64(coveredStmts) / float64(totalStmts) * 100
+}
+return
+}
@@ -2,7 +2,9 @@ package coverage
 import (
 "bufio"
 "fmt"
+"io"
 "os"
+"sort"
 "strconv"
 "strings"
 )
@@ -86,8 +88,34 @@ report.Files[fileName].Blocks = append(report.Files[fileName].Blocks, block)
 if err := scanner.Err(); err != nil {
 return nil, fmt.Errorf("error reading coverage file: %w", err)
 }
+for fileName, fc := range report.Files {
+blocks, err := mergeBlocks(report.Mode, fc.Blocks)
+if err != nil {
+return nil, fmt.Errorf("%s: %w", fileName, err)
+}
+fc.Blocks = blocks
+}
 return report, nil
 }
+func WriteCoverageProfile(w io.Writer, report *CoverageReport) error {
+mode := report.Mode
+if mode == "" {
+mode = "set"
+}
+bw := bufio.NewWriter(w)
+fmt.Fprintf(bw, "mode: %s\n", mode)
+names := make([]string, 0, len(report.Files))
+for name := range report.Files {
+names = append(names, name)
+}
+sort.Strings(names)
+for _, name := range names {
+for _, block := range report.Files[name].Blocks {
+fmt.Fprintf(bw, "%s:%d.%d,%d.%d %d %d\n", name, block.StartLine, block.StartCol, block.EndLine, block.EndCol, block.NumStmt, block.Count)
+}
+}
+return bw.Flush()
+}
 func (fc *FileCoverage) GetCoverageStats() (totalStmts, coveredStmts int, percentage float64) {
 for _, block := range fc.Blocks {
 totalStmts += block.NumStmt
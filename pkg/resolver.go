@@ -0,0 +1,418 @@
+package coverage
+import (
+"archive/tar"
+"archive/zip"
+"bytes"
+"compress/gzip"
+"fmt"
+"io"
+"io/fs"
+"os"
+"os/exec"
+"path"
+"path/filepath"
+"sort"
+"strings"
+"unicode"
+)
+type SourceResolver interface {
+Open(name string) (io.ReadCloser, error)
+}
+type ModuleResolver struct {
+Root       string
+ModulePath string
+ModCache   string
+requires   map[string]string
+replaces   []moduleReplace
+}
+type moduleReplace struct {
+Old        string
+OldVersion string
+New        string
+NewVersion string
+}
+func NewModuleResolver(root string) (*ModuleResolver, error) {
+if root == "" {
+root = "."
+}
+r := &ModuleResolver{
+Root:     root,
+ModCache: defaultModCache(),
+requires: make(map[string]string),
+}
+data, err := os.ReadFile(filepath.Join(root, "go.mod"))
+if os.IsNotExist(err) {
+return r, nil
+}
+if err != nil {
+return nil, fmt.Errorf("failed to read go.mod: %w", err)
+}
+r.parseGoMod(string(data))
+return r, nil
+}
+func defaultModCache() string {
+if dir := os.Getenv("GOMODCACHE"); dir != "" {
+return dir
+}
+if paths := filepath.SplitList(os.Getenv("GOPATH")); len(paths) > 0 && paths[0] != "" {
+return filepath.Join(paths[0], "pkg", "mod")
+}
+if home, err := os.UserHomeDir(); err == nil {
+return filepath.Join(home, "go", "pkg", "mod")
+}
+return ""
+}
+func (r *ModuleResolver) parseGoMod(content string) {
+block := ""
+for _, line := range strings.Split(content, "\n") {
+if i := strings.Index(line, "//"); i >= 0 {
+line = line[:i]
+}
+fields := strings.Fields(line)
+if len(fields) == 0 {
+continue
+}
+if block != "" {
+if fields[0] == ")" {
+block = ""
+continue
+}
+r.parseDirective(block, fields)
+continue
+}
+if len(fields) == 2 && fields[1] == "(" {
+block = fields[0]
+continue
+}
+r.parseDirective(fields[0], fields[1:])
+}
+}
+func (r *ModuleResolver) parseDirective(verb string, args []string) {
+for i := range args {
+args[i] = strings.Trim(args[i], "\"`")
+}
+switch verb {
+case "module":
+if len(args) > 0 {
+r.ModulePath = args[0]
+}
+case "require":
+if len(args) >= 2 {
+r.requires[args[0]] = args[1]
+}
+case "replace":
+arrow := -1
+for i, arg := range args {
+if arg == "=>" {
+arrow = i
+}
+}
+if arrow < 1 || arrow == len(args)-1 {
+return
+}
+rep := moduleReplace{Old: args[0], New: args[arrow+1]}
+if arrow > 1 {
+rep.OldVersion = args[1]
+}
+if arrow+2 < len(args) {
+rep.NewVersion = args[arrow+2]
+}
+r.replaces = append(r.replaces, rep)
+}
+}
+func (r *ModuleResolver) Resolve(name string) (string, error) {
+for _, candidate := range r.candidates(name) {
+if info, err := os.Stat(candidate); err == nil && !info.IsDir() {
+return candidate, nil
+}
+}
+return "", fmt.Errorf("source file not found for %s", name)
+}
+func (r *ModuleResolver) Open(name string) (io.ReadCloser, error) {
+sourcePath, err := r.Resolve(name)
+if err != nil {
+return nil, err
+}
+return os.Open(sourcePath)
+}
+func defaultSourceResolver() SourceResolver {
+resolver, err := NewModuleResolver(".")
+if err != nil {
+return &ModuleResolver{Root: "."}
+}
+return resolver
+}
+func (r *ModuleResolver) candidates(name string) []string {
+if filepath.IsAbs(name) {
+return []string{name}
+}
+slashed := filepath.FromSlash(name)
+candidates := []string{filepath.Join(r.Root, slashed)}
+if rel, ok := trimModulePrefix(name, r.ModulePath); ok {
+candidates = append(candidates, filepath.Join(r.Root, filepath.FromSlash(rel)))
+}
+replaces := append([]moduleReplace{}, r.replaces...)
+sort.SliceStable(replaces, func(i, j int) bool {
+return len(replaces[i].Old) > len(replaces[j].Old)
+})
+for _, rep := range replaces {
+rel, ok := trimModulePrefix(name, rep.Old)
+if !ok {
+continue
+}
+if isLocalModulePath(rep.New) {
+dir := rep.New
+if !filepath.IsAbs(dir) {
+dir = filepath.Join(r.Root, filepath.FromSlash(dir))
+}
+candidates = append(candidates, filepath.Join(dir, filepath.FromSlash(rel)))
+} else if rep.NewVersion != "" {
+candidates = append(candidates, r.modCachePath(rep.New, rep.NewVersion, rel))
+}
+}
+candidates = append(candidates, filepath.Join(r.Root, "vendor", slashed))
+modules := make([]string, 0, len(r.requires))
+for mod := range r.requires {
+modules = append(modules, mod)
+}
+sort.Slice(modules, func(i, j int) bool {
+return len(modules[i]) > len(modules[j])
+})
+for _, mod := range modules {
+if rel, ok := trimModulePrefix(name, mod); ok {
+candidates = append(candidates, r.modCachePath(mod, r.requires[mod], rel))
+}
+}
+return candidates
+}
+func (r *ModuleResolver) modCachePath(mod, version, rel string) string {
+return filepath.Join(r.ModCache, filepath.FromSlash(escapeModulePath(mod))+"@"+version, filepath.FromSlash(rel))
+}
+func trimModulePrefix(name, mod string) (string, bool) {
+if mod == "" {
+return "", false
+}
+if strings.HasPrefix(name, mod+"/") {
+return strings.TrimPrefix(name, mod+"/"), true
+}
+return "", false
+}
+func isLocalModulePath(path string) bool {
+return filepath.IsAbs(path) || strings.HasPrefix(path, "./") || strings.HasPrefix(path, "../") || path == "." || path == ".."
+}
+func escapeModulePath(path string) string {
+var b strings.Builder
+for _, r := range path {
+if unicode.IsUpper(r) {
+b.WriteByte('!')
+b.WriteRune(unicode.ToLower(r))
+continue
+}
+b.WriteRune(r)
+}
+return b.String()
+}
+func modulePathFromGoMod(content []byte) string {
+r := &ModuleResolver{requires: make(map[string]string)}
+r.parseGoMod(string(content))
+return r.ModulePath
+}
+func relativeSourcePaths(name, modulePath string) []string {
+name = strings.TrimPrefix(path.Clean(filepath.ToSlash(name)), "/")
+if rel, ok := trimModulePrefix(name, modulePath); ok {
+return []string{rel, name}
+}
+return []string{name}
+}
+type FSResolver struct {
+FS         fs.FS
+ModulePath string
+}
+func NewFSResolver(fsys fs.FS) *FSResolver {
+r := &FSResolver{FS: fsys}
+if data, err := fs.ReadFile(fsys, "go.mod"); err == nil {
+r.ModulePath = modulePathFromGoMod(data)
+}
+return r
+}
+func (r *FSResolver) Open(name string) (io.ReadCloser, error) {
+for _, candidate := range relativeSourcePaths(name, r.ModulePath) {
+if file, err := r.FS.Open(candidate); err == nil {
+return file, nil
+}
+}
+return nil, fmt.Errorf("source file not found for %s", name)
+}
+type ArchiveResolver struct {
+ModulePath string
+files      map[string][]byte
+}
+func NewArchiveResolver(archivePath string) (*ArchiveResolver, error) {
+var files map[string][]byte
+var err error
+switch lower := strings.ToLower(archivePath); {
+case strings.HasSuffix(lower, ".zip"):
+files, err = readZipArchive(archivePath)
+case strings.HasSuffix(lower, ".tar"):
+files, err = readTarArchive(archivePath, false)
+case strings.HasSuffix(lower, ".tar.gz"), strings.HasSuffix(lower, ".tgz"):
+files, err = readTarArchive(archivePath, true)
+default:
+return nil, fmt.Errorf("unsupported archive format: %s", archivePath)
+}
+if err != nil {
+return nil, fmt.Errorf("failed to read archive: %w", err)
+}
+r := &ArchiveResolver{files: files}
+prefix := ""
+found := false
+for name, content := range files {
+if path.Base(name) != "go.mod" {
+continue
+}
+dir := strings.TrimSuffix(name, "go.mod")
+if !found || len(dir) < len(prefix) {
+found = true
+prefix = dir
+r.ModulePath = modulePathFromGoMod(content)
+}
+}
+if prefix != "" {
+r.files = make(map[string][]byte, len(files))
+for name, content := range files {
+if strings.HasPrefix(name, prefix) {
+r.files[strings.TrimPrefix(name, prefix)] = content
+}
+}
+}
+return r, nil
+}
+func readZipArchive(archivePath string) (map[string][]byte, error) {
+zr, err := zip.OpenReader(archivePath)
+if err != nil {
+return nil, err
+}
+defer zr.Close()
+files := make(map[string][]byte)
+for _, f := range zr.File {
+if f.FileInfo().IsDir() {
+continue
+}
+rc, err := f.Open()
+if err != nil {
+return nil, err
+}
+content, err := io.ReadAll(rc)
+rc.Close()
+if err != nil {
+return nil, err
+}
+files[cleanArchivePath(f.Name)] = content
+}
+return files, nil
+}
+func readTarArchive(archivePath string, gzipped bool) (map[string][]byte, error) {
+file, err := os.Open(archivePath)
+if err != nil {
+return nil, err
+}
+defer file.Close()
+var reader io.Reader = file
+if gzipped {
+gz, err := gzip.NewReader(file)
+if err != nil {
+return nil, err
+}
+defer gz.Close()
+reader = gz
+}
+files := make(map[string][]byte)
+tr := tar.NewReader(reader)
+for {
+header, err := tr.Next()
+if err == io.EOF {
+break
+}
+if err != nil {
+return nil, err
+}
+if header.Typeflag != tar.TypeReg {
+continue
+}
+content, err := io.ReadAll(tr)
+if err != nil {
+return nil, err
+}
+files[cleanArchivePath(header.Name)] = content
+}
+return files, nil
+}
+func cleanArchivePath(name string) string {
+return strings.TrimPrefix(path.Clean("/"+name), "/")
+}
+func (r *ArchiveResolver) Open(name string) (io.ReadCloser, error) {
+for _, candidate := range relativeSourcePaths(name, r.ModulePath) {
+if content, ok := r.files[candidate]; ok {
+return io.NopCloser(bytes.NewReader(content)), nil
+}
+}
+return nil, fmt.Errorf("source file not found in archive for %s", name)
+}
+type GitResolver struct {
+Dir        string
+Rev        string
+ModulePath string
+}
+func NewGitResolver(dir, rev string) (*GitResolver, error) {
+r := &GitResolver{Dir: dir, Rev: rev}
+if _, err := r.git("rev-parse", "--verify", "--quiet", rev+"^{commit}"); err != nil {
+return nil, fmt.Errorf("unknown git revision %q: %w", rev, err)
+}
+if content, err := r.show("go.mod"); err == nil {
+r.ModulePath = modulePathFromGoMod(content)
+}
+return r, nil
+}
+func (r *GitResolver) Open(name string) (io.ReadCloser, error) {
+for _, candidate := range relativeSourcePaths(name, r.ModulePath) {
+if content, err := r.show(candidate); err == nil {
+return io.NopCloser(bytes.NewReader(content)), nil
+}
+}
+return nil, fmt.Errorf("source file not found at %s for %s", r.Rev, name)
+}
+func (r *GitResolver) show(rel string) ([]byte, error) {
+return r.git("show", r.Rev+":./"+rel)
+}
+func (r *GitResolver) git(args ...string) ([]byte, error) {
+return runGit(r.Dir, args...)
+}
+func runGit(dir string, args ...string) ([]byte, error) {
+if dir == "" {
+dir = "."
+}
+cmd := exec.Command("git", append([]string{"-C", dir}, args...)...)
+var stderr bytes.Buffer
+cmd.Stderr = &stderr
+out, err := cmd.Output()
+if err != nil {
+if msg := strings.TrimSpace(stderr.String()); msg != "" {
+return nil, fmt.Errorf("git %s: %s", args[0], msg)
+}
+return nil, fmt.Errorf("git %s: %w", args[0], err)
+}
+return out, nil
+}
+func sourceModulePath(resolver SourceResolver) string {
+switch r := resolver.(type) {
+case *ModuleResolver:
+return r.ModulePath
+case *FSResolver:
+return r.ModulePath
+case *ArchiveResolver:
+return r.ModulePath
+case *GitResolver:
+return r.ModulePath
+}
+return ""
+}
@@ -0,0 +1,261 @@
+package coverage
+import (
+"encoding/json"
+"fmt"
+"html/template"
+"log"
+"net/http"
+"os"
+"strings"
+"sync"
+"time"
+)
+const serverPollInterval = time.Second
+type Server struct {
+resolver SourceResolver
+load     func() (*CoverageReport, error)
+watch    []string
+tmpl     *template.Template
+mu       sync.Mutex
+state    *serverState
+stamps   map[string]FileStamp
+err      error
+changed  chan struct{}
+}
+type ServerSummary struct {
+Mode     string              `json:"mode"`
+Error    string              `json:"error,omitempty"`
+Summary  JSONSummary         `json:"summary"`
+Packages []JSONPackage       `json:"packages"`
+Files    []ServerFileSummary `json:"files"`
+}
+type ServerFileSummary struct {
+Path    string `json:"path"`
+Package string `json:"package"`
+JSONSummary
+}
+type serverState struct {
+report   *CoverageReport
+data     map[string]interface{}
+packages map[string]PackageInfo
+files    map[string]string
+}
+type FileStamp struct {
+ModTime time.Time
+Size    int64
+}
+func StatFile(path string) FileStamp {
+info, err := os.Stat(path)
+if err != nil {
+return FileStamp{}
+}
+return FileStamp{ModTime: info.ModTime(), Size: info.Size()}
+}
+func NewServer(load func() (*CoverageReport, error), resolver SourceResolver, watch []string) (*Server, error) {
+if resolver == nil {
+resolver = defaultSourceResolver()
+}
+tmpl, err := parseHTMLTemplate("site", siteTemplateContent)
+if err != nil {
+return nil, err
+}
+s := &Server{resolver: resolver, load: load, watch: watch, tmpl: tmpl, changed: make(chan struct{})}
+stamps := s.stampWatched()
+report, err := load()
+if err != nil {
+return nil, err
+}
+s.state = newServerState(report)
+s.stamps = stamps
+return s, nil
+}
+func newServerState(report *CoverageReport) *serverState {
+data := htmlReportData(report, collectFileStats(report), false)
+data["Root"] = ""
+data["Title"] = "Go Coverage Report"
+data["LiveReload"] = true
+state := &serverState{
+report:   report,
+data:     data,
+packages: make(map[string]PackageInfo),
+files:    make(map[string]string),
+}
+for _, pkg := range data["Packages"].([]PackageInfo) {
+state.packages[sitePackagePage(pkg.Path)] = pkg
+for _, info := range pkg.Files {
+state.files[siteFilePage(info.Path)] = info.Path
+}
+}
+return state
+}
+func (s *Server) Report() *CoverageReport {
+state, _ := s.current()
+return state.report
+}
+func (s *Server) current() (*serverState, error) {
+s.mu.Lock()
+defer s.mu.Unlock()
+stamps := s.stampWatched()
+changed := false
+for path, stamp := range stamps {
+if s.stamps[path] != stamp {
+changed = true
+}
+}
+if !changed {
+return s.state, s.err
+}
+report, err := s.load()
+if err != nil {
+s.stamps = stamps
+s.err = err
+log.Printf("go-coverage: failed to reload coverage, serving the previous report: %v", err)
+return s.state, s.err
+}
+s.update(newServerState(report), stamps)
+return s.state, nil
+}
+func (s *Server) SetReport(report *CoverageReport) {
+s.mu.Lock()
+defer s.mu.Unlock()
+s.update(newServerState(report), s.stamps)
+}
+func (s *Server) changes() <-chan struct{} {
+s.mu.Lock()
+defer s.mu.Unlock()
+return s.changed
+}
+func (s *Server) update(state *serverState, stamps map[string]FileStamp) {
+s.state = state
+s.stamps = stamps
+s.err = nil
+close(s.changed)
+s.changed = make(chan struct{})
+}
+func (s *Server) stampWatched() map[string]FileStamp {
+stamps := make(map[string]FileStamp, len(s.watch))
+for _, path := range s.watch {
+stamps[path] = StatFile(path)
+}
+return stamps
+}
+func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+if r.Method != http.MethodGet && r.Method != http.MethodHead {
+w.Header().Set("Allow", "GET, HEAD")
+http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+return
+}
+state, loadErr := s.current()
+page := strings.TrimPrefix(r.URL.Path, "/")
+switch {
+case page == "" || page == "index.html":
+s.render(w, "text/html; charset=utf-8", "site-index", state.data)
+case page == "assets/style.css":
+s.render(w, "text/css; charset=utf-8", "styles", nil)
+case page == "assets/report.js":
+s.render(w, "text/javascript; charset=utf-8", "scripts", nil)
+case page == "events":
+s.serveEvents(w, r)
+case page == "api/summary":
+summary := state.summary()
+if loadErr != nil {
+summary.Error = loadErr.Error()
+}
+writeServerJSON(w, summary)
+case strings.HasPrefix(page, "api/files/"):
+name := strings.TrimPrefix(page, "api/files/")
+fc, ok := state.report.Files[name]
+if !ok {
+http.Error(w, fmt.Sprintf("file '%s' not found in coverage report", name), http.StatusNotFound)
+return
+}
+writeServerJSON(w, jsonFile(fc, loadFileInfo(state.report, s.resolver, nil, name)))
+default:
+if pkg, ok := state.packages[page]; ok {
+s.render(w, "text/html; charset=utf-8", "site-package", map[string]interface{}{
+"Root":       siteRoot(page),
+"Title":      "Coverage: " + packageLabel(pkg.Path),
+"Package":    pkg,
+"LiveReload": true,
+})
+return
+}
+if name, ok := state.files[page]; ok {
+s.render(w, "text/html; charset=utf-8", "site-file", map[string]interface{}{
+"Root":       siteRoot(page),
+"Title":      "Coverage: " + name,
+"Package":    PackagePath(name),
+"File":       loadFileInfo(state.report, s.resolver, nil, name),
+"LiveReload": true,
+})
+return
+}
+http.NotFound(w, r)
+}
+}
+func (s *Server) serveEvents(w http.ResponseWriter, r *http.Request) {
+flusher, ok := w.(http.Flusher)
+if !ok {
+http.Error(w, "streaming not supported", http.StatusInternalServerError)
+return
+}
+w.Header().Set("Content-Type", "text/event-stream")
+w.Header().Set("Cache-Control", "no-cache")
+changed := s.changes()
+fmt.Fprint(w, ": connected\n\n")
+flusher.Flush()
+ticker := time.NewTicker(serverPollInterval)
+defer ticker.Stop()
+for {
+select {
+case <-r.Context().Done():
+return
+case <-ticker.C:
+s.current()
+case <-changed:
+changed = s.changes()
+fmt.Fprint(w, "event: reload\ndata: {}\n\n")
+flusher.Flush()
+}
+}
+}
+func (s *Server) render(w http.ResponseWriter, contentType, name string, data interface{}) {
+var b strings.Builder
+if err := s.tmpl.ExecuteTemplate(&b, name, data); err != nil {
+http.Error(w, fmt.Sprintf("failed to execute template: %v", err), http.StatusInternalServerError)
+return
+}
+w.Header().Set("Content-Type", contentType)
+w.Header().Set("Cache-Control", "no-cache")
+fmt.Fprint(w, b.String())
+}
+func (st *serverState) summary() *ServerSummary {
+totalStmts, coveredStmts, overallPct := st.report.GetOverallStats()
+summary := &ServerSummary{
+Mode:     st.report.Mode,
+Summary:  JSONSummary{Statements: totalStmts, Covered: coveredStmts, Coverage: overallPct},
+Packages: []JSONPackage{},
+Files:    []ServerFileSummary{},
+}
+for _, pkg := range st.data["Packages"].([]PackageInfo) {
+summary.Packages = append(summary.Packages, jsonPackage(pkg))
+}
+for _, info := range st.data["Files"].([]FileInfo) {
+summary.Files = append(summary.Files, ServerFileSummary{
+Path:        info.Path,
+Package:     PackagePath(info.Path),
+JSONSummary: JSONSummary{Statements: info.Total, Covered: info.Covered, Coverage: info.Coverage},
+})
+}
+return summary
+}
+func writeServerJSON(w http.ResponseWriter, v interface{}) {
+data, err := json.MarshalIndent(v, "", "  ")
+if err != nil {
+http.Error(w, fmt.Sprintf("failed to encode JSON: %v", err), http.StatusInternalServerError)
+return
+}
+w.Header().Set("Content-Type", "application/json")
+w.Header().Set("Cache-Control", "no-cache")
+w.Write(append(data, '\n'))
+}
@@ -0,0 +1,108 @@
+package coverage
+import (
+"fmt"
+"html/template"
+"os"
+"path/filepath"
+"strings"
+)
+type HTMLSite struct {
+Report   *CoverageReport
+Resolver SourceResolver
+Changes  ChangedLines
+}
+func GenerateHTMLSite(report *CoverageReport, outputDir string) error {
+site := &HTMLSite{Report: report}
+return site.Generate(outputDir)
+}
+func (s *HTMLSite) Generate(outputDir string) error {
+resolver := s.Resolver
+if resolver == nil {
+resolver = defaultSourceResolver()
+}
+tmpl, err := parseHTMLTemplate("site", siteTemplateContent)
+if err != nil {
+return err
+}
+if err := writeSitePage(outputDir, "assets/style.css", tmpl, "styles", nil); err != nil {
+return err
+}
+if err := writeSitePage(outputDir, "assets/report.js", tmpl, "scripts", nil); err != nil {
+return err
+}
+fileInfos := collectFileStats(s.Report)
+changes := s.Changes.Resolve(s.Report, resolver, "")
+patchTotal, patchCovered := 0, 0
+for _, stats := range fileInfos {
+info := loadFileInfo(s.Report, resolver, changes, stats.Path)
+total, covered := info.patchStats()
+patchTotal += total
+patchCovered += covered
+page := siteFilePage(info.Path)
+pageData := map[string]interface{}{
+"Root":    siteRoot(page),
+"Title":   "Coverage: " + info.Path,
+"Package": PackagePath(info.Path),
+"File":    info,
+}
+if err := writeSitePage(outputDir, page, tmpl, "site-file", pageData); err != nil {
+return err
+}
+}
+data := htmlReportData(s.Report, fileInfos, s.Changes != nil)
+setPatchStats(data, patchTotal, patchCovered)
+data["Root"] = ""
+data["Title"] = "Go Coverage Report"
+if err := writeSitePage(outputDir, "index.html", tmpl, "site-index", data); err != nil {
+return err
+}
+for _, pkg := range data["Packages"].([]PackageInfo) {
+page := sitePackagePage(pkg.Path)
+pageData := map[string]interface{}{
+"Root":    siteRoot(page),
+"Title":   "Coverage: " + packageLabel(pkg.Path),
+"Package": pkg,
+}
+if err := writeSitePage(outputDir, page, tmpl, "site-package", pageData); err != nil {
+return err
+}
+}
+return nil
+}
+func writeSitePage(outputDir, page string, tmpl *template.Template, name string, data interface{}) error {
+target := filepath.Join(outputDir, filepath.FromSlash(page))
+if err := os.MkdirAll(filepath.Dir(target), 0o755); err != nil {
+return fmt.Errorf("failed to create output directory: %w", err)
+}
+file, err := os.Create(target)
+if err != nil {
+return fmt.Errorf("failed to create output file: %w", err)
+}
+defer file.Close()
+if err := tmpl.ExecuteTemplate(file, name, data); err != nil {
+return fmt.Errorf("failed to execute template for %s: %w", page, err)
+}
+return nil
+}
+func sitePackagePage(pkgPath string) string {
+return sitePagePath("packages", pkgPath) + "/index.html"
+}
+func siteFilePage(filePath string) string {
+return sitePagePath("files", filePath) + ".html"
+}
+func sitePagePath(dir, name string) string {
+parts := []string{dir}
+for _, part := range strings.Split(name, "/") {
+switch part {
+case "", ".":
+continue
+case "..":
+part = "_"
+}
+parts = append(parts, part)
+}
+return strings.Join(parts, "/")
+}
+func siteRoot(page string) string {
+return strings.Repeat("../", strings.Count(page, "/"))
+}
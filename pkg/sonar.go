@@ -0,0 +1,110 @@
+package coverage
+import (
+"encoding/xml"
+"fmt"
+"io"
+"os"
+"sort"
+"strings"
+)
+type SonarReport struct {
+Report   *CoverageReport
+Resolver SourceResolver
+Rewrites []PathRewrite
+}
+type PathRewrite struct {
+From string
+To   string
+}
+type sonarCoverage struct {
+XMLName xml.Name    `xml:"coverage"`
+Version int         `xml:"version,attr"`
+Files   []sonarFile `xml:"file"`
+}
+type sonarFile struct {
+Path  string      `xml:"path,attr"`
+Lines []sonarLine `xml:"lineToCover"`
+}
+type sonarLine struct {
+LineNumber int  `xml:"lineNumber,attr"`
+Covered    bool `xml:"covered,attr"`
+}
+func ParsePathRewrite(value string) (PathRewrite, error) {
+i := strings.LastIndex(value, "=")
+if i <= 0 {
+return PathRewrite{}, fmt.Errorf("invalid path rewrite '%s', expected <import-path-prefix>=<repository-path-prefix>", value)
+}
+return PathRewrite{From: strings.TrimSuffix(value[:i], "/"), To: strings.TrimSuffix(value[i+1:], "/")}, nil
+}
+func (p PathRewrite) Apply(name string) (string, bool) {
+if name != p.From && !strings.HasPrefix(name, p.From+"/") {
+return "", false
+}
+rest := strings.TrimPrefix(strings.TrimPrefix(name, p.From), "/")
+if p.To == "" {
+return rest, true
+}
+if rest == "" {
+return p.To, true
+}
+return p.To + "/" + rest, true
+}
+func RewritePath(rewrites []PathRewrite, modulePath, name string) string {
+best, bestLen := "", -1
+for _, rewrite := range rewrites {
+if rewritten, ok := rewrite.Apply(name); ok && len(rewrite.From) > bestLen {
+best, bestLen = rewritten, len(rewrite.From)
+}
+}
+if bestLen >= 0 {
+return best
+}
+if rel, ok := trimModulePrefix(name, modulePath); ok {
+return rel
+}
+return name
+}
+func GenerateSonarReport(report *CoverageReport, outputPath string) error {
+sonar := &SonarReport{Report: report}
+return sonar.Generate(outputPath)
+}
+func (s *SonarReport) Generate(outputPath string) error {
+file, err := os.Create(outputPath)
+if err != nil {
+return fmt.Errorf("failed to create output file: %w", err)
+}
+defer file.Close()
+return s.Render(file)
+}
+func (s *SonarReport) Render(w io.Writer) error {
+resolver := s.Resolver
+if resolver == nil {
+resolver = defaultSourceResolver()
+}
+modulePath := sourceModulePath(resolver)
+paths := make([]string, 0, len(s.Report.Files))
+for p := range s.Report.Files {
+paths = append(paths, p)
+}
+sort.Strings(paths)
+doc := sonarCoverage{Version: 1, Files: []sonarFile{}}
+for _, p := range paths {
+file := sonarFile{Path: RewritePath(s.Rewrites, modulePath, p), Lines: []sonarLine{}}
+for _, line := range fileLines(resolver, p, s.Report.Files[p]) {
+if line.Instrumented {
+file.Lines = append(file.Lines, sonarLine{LineNumber: line.LineNumber, Covered: line.IsCovered})
+}
+}
+doc.Files = append(doc.Files, file)
+}
+if _, err := io.WriteString(w, xml.Header); err != nil {
+return err
+}
+enc := xml.NewEncoder(w)
+enc.Indent("", "  ")
+if err := enc.Encode(doc); err != nil {
+return fmt.Errorf("failed to encode sonar report: %w", err)
+}
+_, err := io.WriteString(w, "\n")
+return err
+}
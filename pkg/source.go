@@ -1,26 +1,42 @@
 package coverage
 import (
 "bufio"
+"bytes"
 "fmt"
-"os"
-"path/filepath"
+"io"
 "sort"
 "strings"
 )
 type LineCoverage struct {
-LineNumber int
-Content    string
-Count      int
-IsCovered  bool
+LineNumber   int
+Content      string
+Count        int
+IsCovered    bool
+Instrumented bool
+Changed      bool
+Partial      bool
+Blocks       []CoverageBlock
+Tokens       []SourceToken
+Spans        []LineSpan
+Tooltip      string
+}
+type LineSpan struct {
+Tokens       []SourceToken
+Count        int
+Instrumented bool
 }
 type FileWithSource struct {
-FileName string
-Lines    []LineCoverage
-Total    int
-Covered  int
+FileName  string
+Lines     []LineCoverage
+Functions []FunctionCoverage
+Total     int
+Covered   int
 }
-func GetFileWithSource(filePath string, coverage *FileCoverage) (*FileWithSource, error) {
-file, err := os.Open(filePath)
+func GetFileWithSource(resolver SourceResolver, filePath string, coverage *FileCoverage) (*FileWithSource, error) {
+if resolver == nil {
+resolver = defaultSourceResolver()
+}
+file, err := resolver.Open(filePath)
 if err != nil {
 return &FileWithSource{
 FileName: filePath,
@@ -28,8 +44,12 @@ Lines:    []LineCoverage{},
 }, nil
 }
 defer file.Close()
+src, err := io.ReadAll(file)
+if err != nil {
+return nil, err
+}
 lines := []LineCoverage{}
-scanner := bufio.NewScanner(file)
+scanner := bufio.NewScanner(bytes.NewReader(src))
 lineNum := 0
 for scanner.Scan() {
 lineNum++
@@ -43,22 +63,119 @@ IsCovered:  false,
 if err := scanner.Err(); err != nil {
 return nil, err
 }
-for _, block := range coverage.Blocks {
-for i := block.StartLine; i <= block.EndLine; i++ {
-if i > 0 && i <= len(lines) {
-lines[i-1].Count = block.Count
-lines[i-1].IsCovered = block.Count > 0
+if strings.HasSuffix(filePath, ".go") {
+tokens := HighlightGo(src)
+for i := range lines {
+if i < len(tokens) {
+lines[i].Tokens = tokens[i]
 }
 }
 }
+applyBlocks(lines, coverage.Blocks)
+applySpans(lines)
+functions, _ := GetFunctionCoverage(filePath, src, coverage)
 total, covered, _ := coverage.GetCoverageStats()
 return &FileWithSource{
-FileName: filePath,
-Lines:    lines,
-Total:    total,
-Covered:  covered,
+FileName:  filePath,
+Lines:     lines,
+Functions: functions,
+Total:     total,
+Covered:   covered,
 }, nil
 }
+func applyBlocks(lines []LineCoverage, blocks []CoverageBlock) {
+for _, block := range blocks {
+for i := max(block.StartLine, 1); i <= block.EndLine && i <= len(lines); i++ {
+if i == block.EndLine && i > block.StartLine && block.EndCol == 1 {
+continue
+}
+lines[i-1].Blocks = append(lines[i-1].Blocks, block)
+}
+}
+for i := range lines {
+line := &lines[i]
+covered, uncovered := false, false
+tooltip := []string{}
+for _, block := range line.Blocks {
+line.Count = max(line.Count, block.Count)
+covered = covered || block.Count > 0
+uncovered = uncovered || block.Count == 0
+tooltip = append(tooltip, fmt.Sprintf("block %d.%d,%d.%d: count %d", block.StartLine, block.StartCol, block.EndLine, block.EndCol, block.Count))
+}
+line.Instrumented = len(line.Blocks) > 0
+line.IsCovered = line.Count > 0
+line.Partial = covered && uncovered
+line.Tooltip = strings.Join(tooltip, "\n")
+}
+}
+func applySpans(lines []LineCoverage) {
+for i := range lines {
+line := &lines[i]
+owner := make([]int, len(line.Content))
+for c := range owner {
+owner[c] = -1
+}
+for b, block := range line.Blocks {
+start, end := 0, len(owner)
+if block.StartLine == line.LineNumber {
+start = min(max(block.StartCol-1, 0), len(owner))
+}
+if block.EndLine == line.LineNumber {
+end = min(max(block.EndCol-1, 0), len(owner))
+}
+for c := start; c < end; c++ {
+owner[c] = b
+}
+}
+tokens := line.Tokens
+if tokens == nil && line.Content != "" {
+tokens = []SourceToken{{Text: line.Content}}
+}
+line.Spans = nil
+prev, offset := -2, 0
+for _, tok := range tokens {
+for len(tok.Text) > 0 {
+b := spanOwner(owner, offset)
+n := 1
+for n < len(tok.Text) && spanOwner(owner, offset+n) == b {
+n++
+}
+if b != prev {
+span := LineSpan{}
+if b >= 0 {
+span = LineSpan{Count: line.Blocks[b].Count, Instrumented: true}
+}
+line.Spans = append(line.Spans, span)
+prev = b
+}
+last := &line.Spans[len(line.Spans)-1]
+last.Tokens = append(last.Tokens, SourceToken{Text: tok.Text[:n], Class: tok.Class})
+tok.Text = tok.Text[n:]
+offset += n
+}
+}
+}
+}
+func spanOwner(owner []int, offset int) int {
+if offset < len(owner) {
+return owner[offset]
+}
+return -1
+}
+func linesFromBlocks(coverage *FileCoverage) []LineCoverage {
+lastLine := 0
+for _, block := range coverage.Blocks {
+if block.EndLine > lastLine {
+lastLine = block.EndLine
+}
+}
+lines := make([]LineCoverage, lastLine)
+for i := range lines {
+lines[i].LineNumber = i + 1
+}
+applyBlocks(lines, coverage.Blocks)
+return lines
+}
 type FileNode struct {
 Name     string
 Path     string
@@ -74,7 +191,7 @@ IsDir:    true,
 Children: []*FileNode{},
 }
 for path, coverage := range files {
-parts := strings.Split(path, string(filepath.Separator))
+parts := strings.Split(path, "/")
 current := root
 for i, part := range parts {
 isLast := i == len(parts)-1
@@ -103,6 +220,16 @@ current = child
 sortFileTree(root)
 return root
 }
+func CompactFileTree(node *FileNode) *FileNode {
+for i, child := range node.Children {
+for child.IsDir && len(child.Children) == 1 && child.Children[0].IsDir {
+grandchild := child.Children[0]
+child = &FileNode{Name: child.Name + "/" + grandchild.Name, Path: grandchild.Path, IsDir: true, Children: grandchild.Children}
+}
+node.Children[i] = CompactFileTree(child)
+}
+return node
+}
 func sortFileTree(node *FileNode) {
 sort.Slice(node.Children, func(i, j int) bool {
 if node.Children[i].IsDir != node.Children[j].IsDir {
@@ -115,33 +242,12 @@ sortFileTree(child)
 }
 }
 func (n *FileNode) GetCoveragePercentage() float64 {
-if !n.IsDir && n.Coverage != nil {
-_, _, pct := n.Coverage.GetCoverageStats()
-return pct
-}
-if n.IsDir {
-total := 0
-covered := 0
-for _, child := range n.Children {
-if child.Coverage != nil {
-t, c, _ := child.Coverage.GetCoverageStats()
-total += t
-covered += c
-} else if child.IsDir {
-pct := child.GetCoveragePercentage()
-if pct > 0 {
-t, c := child.GetTotalStatements()
-total += t
-covered += c
-}
-}
+total, covered := n.GetTotalStatements()
+if total == 0 {
+return 0.0
 }
-if total > 0 {
 return float64(covered) / float64(total) * 100
 }
-}
-return 0.0
-}
 func (n *FileNode) GetTotalStatements() (total, covered int) {
 if !n.IsDir && n.Coverage != nil {
 t, c, _ := n.Coverage.GetCoverageStats()
@@ -173,3 +279,19 @@ return "#f44336"
 func FormatPercentage(pct float64) string {
 return fmt.Sprintf("%.1f%%", pct)
 }
+func FormatLineRanges(lines []int) string {
+parts := []string{}
+for i := 0; i < len(lines); {
+j := i
+for j+1 < len(lines) && lines[j+1] == lines[j]+1 {
+j++
+}
+if i == j {
+parts = append(parts, fmt.Sprintf("%d", lines[i]))
+} else {
+parts = append(parts, fmt.Sprintf("%d-%d", lines[i], lines[j]))
+}
+i = j + 1
+}
+return strings.Join(parts, ", ")
+}
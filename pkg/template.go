@@ -1,11 +1,5 @@
 package coverage
-const htmlTemplateContent = `<!DOCTYPE html>
-<html lang="en">
-<head>
-    <meta charset="UTF-8">
-    <meta name="viewport" content="width=device-width, initial-scale=1.0">
-    <title>Go Coverage Report</title>
-    <style>
+const htmlStylesTemplate = `{{define "styles"}}
         * { margin: 0; padding: 0; box-sizing: border-box; }
         body { font-family: -apple-system, BlinkMacSystemFont, 'Segoe UI', Roboto, sans-serif; background: #f6f8fa; color: #24292e; line-height: 1.5; }
         .header { background: #24292e; color: white; padding: 20px; box-shadow: 0 2px 4px rgba(0,0,0,0.1); }
@@ -22,9 +16,19 @@ const htmlTemplateContent = `<!DOCTYPE html>
         .tree-node { padding: 6px 20px; cursor: pointer; display: flex; align-items: center; gap: 8px; transition: background 0.2s; }
         .tree-node:hover { background: #f6f8fa; }
         .tree-node.active { background: #e1e4e8; font-weight: 600; }
+        .tree-name { flex: 1; overflow: hidden; text-overflow: ellipsis; white-space: nowrap; }
+        .tree-toggle { width: 12px; color: #586069; font-size: 10px; transition: transform 0.2s; }
+        .tree-dir.expanded > .tree-node > .tree-toggle { transform: rotate(90deg); }
+        .tree-children { display: none; padding-left: 14px; }
+        .tree-dir.expanded > .tree-children { display: block; }
         .tree-icon { width: 16px; font-size: 12px; }
         .tree-coverage { margin-left: auto; font-size: 12px; padding: 2px 6px; border-radius: 6px; font-weight: 600; color: white; }
         .content { flex: 1; padding: 20px; overflow-x: auto; }
+        .breadcrumbs { font-size: 14px; opacity: 0.9; margin-bottom: 5px; }
+        .breadcrumbs a { color: white; }
+        a.tree-node { color: inherit; text-decoration: none; }
+        .path-cell a { color: #0366d6; text-decoration: none; }
+        .path-cell a:hover { text-decoration: underline; }
         .file-section { background: white; border-radius: 6px; margin-bottom: 20px; border: 1px solid #e1e4e8; overflow: hidden; }
         .file-header { padding: 15px 20px; background: #f6f8fa; border-bottom: 1px solid #e1e4e8; display: flex; justify-content: space-between; align-items: center; }
         .file-name { font-weight: 600; font-size: 16px; font-family: monospace; }
@@ -34,9 +38,20 @@ const htmlTemplateContent = `<!DOCTYPE html>
         .code-table td { padding: 0; vertical-align: top; }
         .line-number { width: 50px; text-align: right; padding: 2px 10px; color: #6a737d; user-select: none; background: #f6f8fa; border-right: 1px solid #e1e4e8; }
         .line-content { padding: 2px 10px; white-space: pre; overflow-x: auto; }
+        .tok-keyword { color: #d73a49; font-weight: 600; }
+        .tok-string { color: #032f62; }
+        .tok-comment { color: #6a737d; font-style: italic; }
+        .tok-number { color: #005cc5; }
+        .tok-ident { color: #24292e; }
+        .tok-builtin { color: #6f42c1; }
         .line-covered { background: #e6ffed; }
         .line-uncovered { background: #ffeef0; }
+        .line-partial { background: #fffbdd; }
+        .cov-hit { background: #acf2bd; border-radius: 2px; }
+        .cov-miss { background: #fdb8c0; border-radius: 2px; }
         .line-neutral { background: white; }
+        .line-changed .line-number { border-left: 4px solid #0366d6; font-weight: 600; color: #0366d6; }
+        .line-changed.line-uncovered .line-number { border-left-color: #d73a49; color: #d73a49; }
         .no-source { padding: 40px; text-align: center; color: #6a737d; }
         .summary-table { width: 100%; border-collapse: collapse; background: white; border-radius: 6px; overflow: hidden; }
         .summary-table th { background: #f6f8fa; padding: 12px 15px; text-align: left; font-weight: 600; border-bottom: 1px solid #e1e4e8; }
@@ -46,42 +61,85 @@ const htmlTemplateContent = `<!DOCTYPE html>
         .path-cell { font-family: monospace; font-size: 13px; }
         .coverage-cell { text-align: center; width: 100px; }
         .statements-cell { text-align: center; width: 120px; font-size: 13px; color: #6a737d; }
+        .package-row { cursor: pointer; font-weight: 600; }
+        .package-toggle { display: inline-block; width: 16px; font-size: 10px; color: #6a737d; transition: transform 0.2s; }
+        .package-row.expanded .package-toggle { transform: rotate(90deg); }
+        .package-file-row { display: none; cursor: pointer; background: #fafbfc; }
+        .package-file-row.visible { display: table-row; }
+        .package-file-row .path-cell { padding-left: 40px; }
+        .function-table { border-bottom: 1px solid #e1e4e8; }
+        .function-table th { cursor: pointer; user-select: none; }
+        .function-table th.sorted-asc::after { content: " ▲"; font-size: 10px; }
+        .function-table th.sorted-desc::after { content: " ▼"; font-size: 10px; }
+        .function-table tr { cursor: pointer; }
+        .line-highlight td { box-shadow: inset 0 0 0 9999px rgba(255, 213, 79, 0.35); }
+        .delta { font-weight: 600; font-size: 13px; white-space: nowrap; }
+        .delta-up { color: #22863a; }
+        .delta-down { color: #cb2431; }
+        .delta-none { color: #6a737d; }
+        .status-badge { display: inline-block; padding: 2px 8px; border-radius: 10px; font-size: 11px; font-weight: 600; text-transform: uppercase; background: #e1e4e8; color: #24292e; }
+        .status-added { background: #dcffe4; color: #22863a; }
+        .status-removed { background: #ffdce0; color: #cb2431; }
+        .lines-cell { font-family: monospace; font-size: 12px; color: #cb2431; }
         .section-title { font-size: 20px; font-weight: 600; margin-bottom: 15px; padding-bottom: 10px; border-bottom: 2px solid #e1e4e8; }
+{{end}}`
+const htmlTemplateContent = `<!DOCTYPE html>
+<html lang="en">
+<head>
+    <meta charset="UTF-8">
+    <meta name="viewport" content="width=device-width, initial-scale=1.0">
+    <title>Go Coverage Report</title>
+    <style>
+{{template "styles"}}
     </style>
 </head>
 <body>
     <div class="header">
         <h1>📊 Go Coverage Report</h1>
-        <div class="overall-stats">
-            <div class="stat">
-                <span class="stat-label">Overall Coverage:</span>
-                <span class="coverage-badge" style="background: {{.OverallColor}}">{{formatPct .OverallPct}}</span>
-            </div>
-            <div class="stat">
-                <span class="stat-label">Statements:</span>
-                <span class="stat-value">{{.CoveredStmts}} / {{.TotalStmts}}</span>
-            </div>
-            <div class="stat">
-                <span class="stat-label">Mode:</span>
-                <span class="stat-value">{{.Mode}}</span>
-            </div>
-        </div>
+        {{template "overall-stats" .}}
     </div>
     <div class="container">
         <div class="sidebar">
             <div class="sidebar-header">📁 Files</div>
             <div class="file-tree">
-                {{range .Files}}
-                <div class="tree-node" onclick="scrollToFile('{{.Path}}')" data-file="{{.Path}}">
-                    <span class="tree-icon">📄</span>
-                    <span>{{.Name}}</span>
-                    <span class="tree-coverage" style="background: {{.Color}}">{{formatPct .Coverage}}</span>
-                </div>
-                {{end}}
+                {{template "tree-node" .FileTree}}
             </div>
         </div>
         <div class="content">
-            <div class="section-title">Coverage Summary</div>
+            <div class="section-title">Package Summary</div>
+            <div class="file-section">
+                <table class="summary-table">
+                    <thead>
+                        <tr>
+                            <th>Package</th>
+                            <th class="coverage-cell">Coverage</th>
+                            <th class="statements-cell">Statements</th>
+                        </tr>
+                    </thead>
+                    <tbody>
+                        {{range .Packages}}
+                        {{$pkg := .Path}}
+                        <tr class="package-row" onclick="togglePackage(this, '{{$pkg}}')">
+                            <td class="path-cell"><span class="package-toggle">▶</span>{{if $pkg}}{{$pkg}}{{else}}(root){{end}}</td>
+                            <td class="coverage-cell">
+                                <span class="coverage-badge" style="background: {{.Color}}">{{formatPct .Coverage}}</span>
+                            </td>
+                            <td class="statements-cell">{{.Covered}} / {{.Total}}</td>
+                        </tr>
+                        {{range .Files}}
+                        <tr class="package-file-row" data-package="{{$pkg}}" onclick="scrollToFile('{{.Path}}')">
+                            <td class="path-cell">{{.Name}}</td>
+                            <td class="coverage-cell">
+                                <span class="coverage-badge" style="background: {{.Color}}">{{formatPct .Coverage}}</span>
+                            </td>
+                            <td class="statements-cell">{{.Covered}} / {{.Total}}</td>
+                        </tr>
+                        {{end}}
+                        {{end}}
+                    </tbody>
+                </table>
+            </div>
+            <div class="section-title" style="margin-top: 40px;">Coverage Summary</div>
             <div class="file-section">
                 <table class="summary-table">
                     <thead>
@@ -107,40 +165,389 @@ const htmlTemplateContent = `<!DOCTYPE html>
             <div class="section-title" style="margin-top: 40px;">File Details</div>
             {{range .Files}}
             <div class="file-section" id="file-{{.Path}}">
-                <div class="file-header">
-                    <div class="file-name">{{.Path}}</div>
-                    <div class="file-stats">
-                        <span>{{.Covered}} / {{.Total}} statements</span>
-                        <span class="coverage-badge" style="background: {{.Color}}">{{formatPct .Coverage}}</span>
-                    </div>
-                </div>
-                {{if .HasSource}}
-                <div class="code-container">
-                    <table class="code-table">
-                        {{range .Lines}}
-                        <tr class="{{if .IsCovered}}line-covered{{else if eq .Count 0}}line-uncovered{{else}}line-neutral{{end}}">
-                            <td class="line-number">{{.LineNumber}}</td>
-                            <td class="line-content">{{.Content}}</td>
-                        </tr>
-                        {{end}}
-                    </table>
-                </div>
-                {{else}}
-                <div class="no-source">Source file not found in current directory</div>
-                {{end}}
+                {{template "file-detail" .}}
             </div>
             {{end}}
         </div>
     </div>
     <script>
-        function scrollToFile(filePath) {
-            const element = document.getElementById('file-' + filePath);
-            if (element) {
-                element.scrollIntoView({ behavior: 'smooth', block: 'start' });
-                document.querySelectorAll('.tree-node').forEach(node => node.classList.remove('active'));
-                document.querySelector('.tree-node[data-file="' + filePath + '"]').classList.add('active');
-            }
-        }
+{{template "scripts"}}
     </script>
 </body>
 </html>`
+const htmlPartialsTemplate = `{{define "overall-stats"}}
+<div class="overall-stats">
+    <div class="stat">
+        <span class="stat-label">Overall Coverage:</span>
+        <span class="coverage-badge" style="background: {{.OverallColor}}">{{formatPct .OverallPct}}</span>
+    </div>
+    <div class="stat">
+        <span class="stat-label">Statements:</span>
+        <span class="stat-value">{{.CoveredStmts}} / {{.TotalStmts}}</span>
+    </div>
+    {{if .HasPatch}}
+    <div class="stat">
+        <span class="stat-label">Patch Coverage:</span>
+        <span class="coverage-badge" style="background: {{.PatchColor}}">{{formatPct .PatchPct}}</span>
+        <span class="stat-value">{{.PatchCovered}} / {{.PatchTotal}} lines</span>
+    </div>
+    {{end}}
+    <div class="stat">
+        <span class="stat-label">Mode:</span>
+        <span class="stat-value">{{.Mode}}</span>
+    </div>
+</div>{{end}}
+{{define "file-detail"}}{{$path := .Path}}
+<div class="file-header">
+    <div class="file-name">{{.Path}}</div>
+    <div class="file-stats">
+        <span>{{.Covered}} / {{.Total}} statements</span>
+        <span class="coverage-badge" style="background: {{.Color}}">{{formatPct .Coverage}}</span>
+    </div>
+</div>
+{{if .Functions}}
+<table class="summary-table function-table">
+    <thead>
+        <tr>
+            <th onclick="sortTable(this, 'text')">Function</th>
+            <th class="statements-cell" onclick="sortTable(this, 'number')">Line</th>
+            <th class="coverage-cell" onclick="sortTable(this, 'number')">Coverage</th>
+            <th class="statements-cell" onclick="sortTable(this, 'number')">Statements</th>
+        </tr>
+    </thead>
+    <tbody>
+        {{range .Functions}}
+        <tr onclick="scrollToLine('{{$path}}', {{.StartLine}})">
+            <td class="path-cell" data-sort="{{.Name}}">{{.Name}}</td>
+            <td class="statements-cell" data-sort="{{.StartLine}}">{{.StartLine}}</td>
+            <td class="coverage-cell" data-sort="{{.Coverage}}">
+                <span class="coverage-badge" style="background: {{getCoverageColor .Coverage}}">{{formatPct .Coverage}}</span>
+            </td>
+            <td class="statements-cell" data-sort="{{.Total}}">{{.Covered}} / {{.Total}}</td>
+        </tr>
+        {{end}}
+    </tbody>
+</table>
+{{end}}
+{{if .HasSource}}
+<div class="code-container">
+    <table class="code-table">
+        {{range .Lines}}
+        <tr id="line-{{$path}}-{{.LineNumber}}" class="{{if .Partial}}line-partial{{else if .IsCovered}}line-covered{{else if .Instrumented}}line-uncovered{{else}}line-neutral{{end}}{{if .Changed}} line-changed{{end}}"{{if .Tooltip}} title="{{.Tooltip}}"{{end}}>
+            <td class="line-number">{{.LineNumber}}</td>
+            <td class="line-content">{{if .Spans}}{{range .Spans}}{{if .Instrumented}}<span class="{{if .Count}}cov-hit{{else}}cov-miss{{end}}">{{template "tokens" .Tokens}}</span>{{else}}{{template "tokens" .Tokens}}{{end}}{{end}}{{else}}{{.Content}}{{end}}</td>
+        </tr>
+        {{end}}
+    </table>
+</div>
+{{else}}
+<div class="no-source">Source file not found</div>
+{{end}}{{end}}
+{{define "tokens"}}{{range .}}{{if .Class}}<span class="tok-{{.Class}}">{{.Text}}</span>{{else}}{{.Text}}{{end}}{{end}}{{end}}
+{{define "tree-node"}}{{range .Children}}{{if .IsDir}}
+<div class="tree-dir" data-dir="{{.Path}}">
+    <div class="tree-node" onclick="toggleDir(this.parentNode)" title="{{.Path}}">
+        <span class="tree-toggle">▸</span>
+        <span class="tree-icon">📁</span>
+        <span class="tree-name">{{.Name}}</span>
+        <span class="tree-coverage" style="background: {{getCoverageColor .GetCoveragePercentage}}">{{formatPct .GetCoveragePercentage}}</span>
+    </div>
+    <div class="tree-children">{{template "tree-node" .}}</div>
+</div>{{else}}
+<div class="tree-node" onclick="scrollToFile('{{.Path}}')" data-file="{{.Path}}" title="{{.Path}}">
+    <span class="tree-toggle"></span>
+    <span class="tree-icon">📄</span>
+    <span class="tree-name">{{.Name}}</span>
+    <span class="tree-coverage" style="background: {{getCoverageColor .GetCoveragePercentage}}">{{formatPct .GetCoveragePercentage}}</span>
+</div>{{end}}{{end}}{{end}}
+{{define "scripts"}}
+function scrollToFile(filePath) {
+    const element = document.getElementById('file-' + filePath);
+    if (element) {
+        element.scrollIntoView({ behavior: 'smooth', block: 'start' });
+        document.querySelectorAll('.tree-node').forEach(node => node.classList.remove('active'));
+        const node = document.querySelector('.tree-node[data-file="' + filePath + '"]');
+        if (node) {
+            node.classList.add('active');
+            expandToFile(node);
+        }
+    }
+}
+const treeStateKey = 'go-coverage-tree:' + location.pathname;
+function saveTreeState() {
+    const expanded = Array.from(document.querySelectorAll('.tree-dir.expanded')).map(dir => dir.dataset.dir);
+    try {
+        localStorage.setItem(treeStateKey, JSON.stringify(expanded));
+    } catch (e) {}
+}
+function toggleDir(dir) {
+    dir.classList.toggle('expanded');
+    saveTreeState();
+}
+function expandToFile(node) {
+    for (let dir = node.closest('.tree-dir'); dir; dir = dir.parentNode.closest('.tree-dir')) {
+        dir.classList.add('expanded');
+    }
+    saveTreeState();
+}
+function restoreTreeState() {
+    let expanded = null;
+    try {
+        expanded = JSON.parse(localStorage.getItem(treeStateKey));
+    } catch (e) {}
+    if (!Array.isArray(expanded)) {
+        document.querySelectorAll('.file-tree > .tree-dir').forEach(dir => dir.classList.add('expanded'));
+        return;
+    }
+    const open = new Set(expanded);
+    document.querySelectorAll('.tree-dir').forEach(dir => dir.classList.toggle('expanded', open.has(dir.dataset.dir)));
+}
+restoreTreeState();
+function togglePackage(row, pkg) {
+    const expanded = row.classList.toggle('expanded');
+    document.querySelectorAll('.package-file-row').forEach(fileRow => {
+        if (fileRow.dataset.package === pkg) {
+            fileRow.classList.toggle('visible', expanded);
+        }
+    });
+}
+function scrollToLine(filePath, line) {
+    const element = document.getElementById('line-' + filePath + '-' + line);
+    if (element) {
+        element.scrollIntoView({ behavior: 'smooth', block: 'center' });
+        document.querySelectorAll('.line-highlight').forEach(row => row.classList.remove('line-highlight'));
+        element.classList.add('line-highlight');
+    }
+}
+function sortTable(header, type) {
+    const table = header.closest('table');
+    const tbody = table.querySelector('tbody');
+    const index = Array.from(header.parentNode.children).indexOf(header);
+    const ascending = !header.classList.contains('sorted-asc');
+    header.parentNode.querySelectorAll('th').forEach(th => th.classList.remove('sorted-asc', 'sorted-desc'));
+    header.classList.add(ascending ? 'sorted-asc' : 'sorted-desc');
+    const rows = Array.from(tbody.querySelectorAll('tr'));
+    rows.sort((a, b) => {
+        const x = a.children[index].dataset.sort;
+        const y = b.children[index].dataset.sort;
+        const cmp = type === 'number' ? parseFloat(x) - parseFloat(y) : x.localeCompare(y);
+        return ascending ? cmp : -cmp;
+    });
+    rows.forEach(row => tbody.appendChild(row));
+}
+{{end}}`
+const compareTemplateContent = `<!DOCTYPE html>
+<html lang="en">
+<head>
+    <meta charset="UTF-8">
+    <meta name="viewport" content="width=device-width, initial-scale=1.0">
+    <title>Go Coverage Comparison</title>
+    <style>
+{{template "styles"}}
+    </style>
+</head>
+<body>
+    <div class="header">
+        <h1>📊 Go Coverage Comparison</h1>
+        <div class="overall-stats">
+            <div class="stat">
+                <span class="stat-label">Base:</span>
+                <span class="coverage-badge" style="background: {{getCoverageColor .BaseCoverage}}">{{formatPct .BaseCoverage}}</span>
+                <span class="stat-value">{{.BaseCovered}} / {{.BaseTotal}}</span>
+            </div>
+            <div class="stat">
+                <span class="stat-label">Head:</span>
+                <span class="coverage-badge" style="background: {{getCoverageColor .HeadCoverage}}">{{formatPct .HeadCoverage}}</span>
+                <span class="stat-value">{{.HeadCovered}} / {{.HeadTotal}}</span>
+            </div>
+            <div class="stat">
+                <span class="stat-label">Delta:</span>
+                <span class="stat-value">{{deltaArrow .Delta}} {{formatDelta .Delta}}</span>
+            </div>
+        </div>
+    </div>
+    <div class="content">
+        <div class="section-title">Packages</div>
+        <div class="file-section">
+            <table class="summary-table">
+                <thead>
+                    <tr>
+                        <th>Package</th>
+                        <th class="coverage-cell">Base</th>
+                        <th class="coverage-cell">Head</th>
+                        <th class="statements-cell">Delta</th>
+                    </tr>
+                </thead>
+                <tbody>
+                    {{range .Packages}}
+                    <tr>
+                        <td class="path-cell">{{if .Path}}{{.Path}}{{else}}(root){{end}}</td>
+                        {{template "compare-cells" .}}
+                    </tr>
+                    {{end}}
+                </tbody>
+            </table>
+        </div>
+        <div class="section-title" style="margin-top: 40px;">Files</div>
+        <div class="file-section">
+            <table class="summary-table">
+                <thead>
+                    <tr>
+                        <th>File</th>
+                        <th class="coverage-cell">Base</th>
+                        <th class="coverage-cell">Head</th>
+                        <th class="statements-cell">Delta</th>
+                        <th>Newly Uncovered Lines</th>
+                    </tr>
+                </thead>
+                <tbody>
+                    {{range .Files}}
+                    <tr>
+                        <td class="path-cell">{{.Path}}</td>
+                        {{template "compare-cells" .}}
+                        <td class="lines-cell">{{if .Unmatched}}sources changed, lines not compared{{else}}{{formatLines .NewlyUncovered}}{{end}}</td>
+                    </tr>
+                    {{end}}
+                </tbody>
+            </table>
+        </div>
+    </div>
+</body>
+</html>
+{{define "compare-cells"}}
+                        <td class="coverage-cell">
+                            {{if eq .Status "added"}}<span class="status-badge status-added">new</span>{{else}}<span class="coverage-badge" style="background: {{getCoverageColor .BaseCoverage}}">{{formatPct .BaseCoverage}}</span>{{end}}
+                        </td>
+                        <td class="coverage-cell">
+                            {{if eq .Status "removed"}}<span class="status-badge status-removed">removed</span>{{else}}<span class="coverage-badge" style="background: {{getCoverageColor .HeadCoverage}}">{{formatPct .HeadCoverage}}</span>{{end}}
+                        </td>
+                        <td class="statements-cell">
+                            {{if or (eq .Status "added") (eq .Status "removed")}}<span class="delta delta-none">–</span>{{else}}<span class="delta {{if eq .Status "increased"}}delta-up{{else if eq .Status "decreased"}}delta-down{{else}}delta-none{{end}}">{{deltaArrow .Delta}} {{formatDelta .Delta}}</span>{{end}}
+                        </td>
+{{end}}`
+const siteTemplateContent = `{{define "site-head"}}<!DOCTYPE html>
+<html lang="en">
+<head>
+    <meta charset="UTF-8">
+    <meta name="viewport" content="width=device-width, initial-scale=1.0">
+    <title>{{.Title}}</title>
+    <link rel="stylesheet" href="{{.Root}}assets/style.css">
+</head>
+<body>{{end}}
+{{define "site-foot"}}
+    <script src="{{.Root}}assets/report.js"></script>
+    {{if .LiveReload}}
+    <script>
+        new EventSource('{{.Root}}events').addEventListener('reload', () => location.reload());
+    </script>
+    {{end}}
+</body>
+</html>
+{{end}}
+{{define "site-index"}}{{template "site-head" .}}
+    <div class="header">
+        <h1>📊 Go Coverage Report</h1>
+        {{template "overall-stats" .}}
+    </div>
+    <div class="container">
+        <div class="sidebar">
+            <div class="sidebar-header">📁 Files</div>
+            <div class="file-tree">
+                {{template "site-tree-node" .FileTree}}
+            </div>
+        </div>
+        <div class="content">
+            <div class="section-title">Package Summary</div>
+            <div class="file-section">
+                <table class="summary-table">
+                    <thead>
+                        <tr>
+                            <th onclick="sortTable(this, 'text')">Package</th>
+                            <th class="coverage-cell" onclick="sortTable(this, 'number')">Coverage</th>
+                            <th class="statements-cell" onclick="sortTable(this, 'number')">Statements</th>
+                        </tr>
+                    </thead>
+                    <tbody>
+                        {{range .Packages}}
+                        <tr>
+                            <td class="path-cell" data-sort="{{.Path}}"><a href="{{packagePage .Path}}">{{if .Path}}{{.Path}}{{else}}(root){{end}}</a></td>
+                            <td class="coverage-cell" data-sort="{{.Coverage}}">
+                                <span class="coverage-badge" style="background: {{.Color}}">{{formatPct .Coverage}}</span>
+                            </td>
+                            <td class="statements-cell" data-sort="{{.Total}}">{{.Covered}} / {{.Total}}</td>
+                        </tr>
+                        {{end}}
+                    </tbody>
+                </table>
+            </div>
+        </div>
+    </div>
+{{template "site-foot" .}}{{end}}
+{{define "site-package"}}{{template "site-head" .}}
+    <div class="header">
+        <div class="breadcrumbs"><a href="{{.Root}}index.html">Overview</a> › {{if .Package.Path}}{{.Package.Path}}{{else}}(root){{end}}</div>
+        <h1>📦 {{if .Package.Path}}{{.Package.Path}}{{else}}(root){{end}}</h1>
+        <div class="overall-stats">
+            <div class="stat">
+                <span class="stat-label">Coverage:</span>
+                <span class="coverage-badge" style="background: {{.Package.Color}}">{{formatPct .Package.Coverage}}</span>
+            </div>
+            <div class="stat">
+                <span class="stat-label">Statements:</span>
+                <span class="stat-value">{{.Package.Covered}} / {{.Package.Total}}</span>
+            </div>
+        </div>
+    </div>
+    <div class="content">
+        <div class="section-title">Files</div>
+        <div class="file-section">
+            <table class="summary-table">
+                <thead>
+                    <tr>
+                        <th onclick="sortTable(this, 'text')">File</th>
+                        <th class="coverage-cell" onclick="sortTable(this, 'number')">Coverage</th>
+                        <th class="statements-cell" onclick="sortTable(this, 'number')">Statements</th>
+                    </tr>
+                </thead>
+                <tbody>
+                    {{range .Package.Files}}
+                    <tr>
+                        <td class="path-cell" data-sort="{{.Name}}"><a href="{{$.Root}}{{filePage .Path}}">{{.Name}}</a></td>
+                        <td class="coverage-cell" data-sort="{{.Coverage}}">
+                            <span class="coverage-badge" style="background: {{.Color}}">{{formatPct .Coverage}}</span>
+                        </td>
+                        <td class="statements-cell" data-sort="{{.Total}}">{{.Covered}} / {{.Total}}</td>
+                    </tr>
+                    {{end}}
+                </tbody>
+            </table>
+        </div>
+    </div>
+{{template "site-foot" .}}{{end}}
+{{define "site-file"}}{{template "site-head" .}}
+    <div class="header">
+        <div class="breadcrumbs"><a href="{{.Root}}index.html">Overview</a> › <a href="{{.Root}}{{packagePage .Package}}">{{if .Package}}{{.Package}}{{else}}(root){{end}}</a> › {{.File.Name}}</div>
+        <h1>📄 {{.File.Name}}</h1>
+    </div>
+    <div class="content">
+        <div class="file-section" id="file-{{.File.Path}}">
+            {{template "file-detail" .File}}
+        </div>
+    </div>
+{{template "site-foot" .}}{{end}}
+{{define "site-tree-node"}}{{range .Children}}{{if .IsDir}}
+<div class="tree-dir" data-dir="{{.Path}}">
+    <div class="tree-node" onclick="toggleDir(this.parentNode)" title="{{.Path}}">
+        <span class="tree-toggle">▸</span>
+        <span class="tree-icon">📁</span>
+        <span class="tree-name">{{.Name}}</span>
+        <span class="tree-coverage" style="background: {{getCoverageColor .GetCoveragePercentage}}">{{formatPct .GetCoveragePercentage}}</span>
+    </div>
+    <div class="tree-children">{{template "site-tree-node" .}}</div>
+</div>{{else}}
+<a class="tree-node" href="{{filePage .Path}}" data-file="{{.Path}}" title="{{.Path}}">
+    <span class="tree-toggle"></span>
+    <span class="tree-icon">📄</span>
+    <span class="tree-name">{{.Name}}</span>
+    <span class="tree-coverage" style="background: {{getCoverageColor .GetCoveragePercentage}}">{{formatPct .GetCoveragePercentage}}</span>
+</a>{{end}}{{end}}{{end}}`
@@ -0,0 +1,130 @@
+package coverage
+import (
+"bufio"
+"fmt"
+"io"
+"os"
+"sort"
+"strings"
+"unicode/utf8"
+)
+type TextReport struct {
+Report        *CoverageReport
+Resolver      SourceResolver
+Color         bool
+ShowUncovered bool
+SortBy        string
+}
+type textRow struct {
+name      string
+pct       float64
+total     int
+covered   int
+uncovered string
+}
+func GenerateTextReport(report *CoverageReport, outputPath string) error {
+text := &TextReport{Report: report}
+return text.Generate(outputPath)
+}
+func (t *TextReport) Generate(outputPath string) error {
+file, err := os.Create(outputPath)
+if err != nil {
+return fmt.Errorf("failed to create output file: %w", err)
+}
+defer file.Close()
+return t.Render(file)
+}
+func (t *TextReport) Render(w io.Writer) error {
+if t.SortBy != "" && t.SortBy != "path" && t.SortBy != "coverage" {
+return fmt.Errorf("unknown sort order '%s'", t.SortBy)
+}
+resolver := t.Resolver
+if resolver == nil {
+resolver = defaultSourceResolver()
+}
+fileInfos := collectFileInfos(t.Report, resolver, nil)
+packageRows := []textRow{}
+for _, pkg := range collectPackageInfos(t.Report, fileInfos) {
+packageRows = append(packageRows, textRow{name: packageLabel(pkg.Path), pct: pkg.Coverage, total: pkg.Total, covered: pkg.Covered})
+}
+fileRows := []textRow{}
+for _, info := range fileInfos {
+row := textRow{name: info.Path, pct: info.Coverage, total: info.Total, covered: info.Covered}
+if t.ShowUncovered {
+lines := info.Lines
+if !info.HasSource {
+lines = linesFromBlocks(t.Report.Files[info.Path])
+}
+uncovered := []int{}
+for _, line := range lines {
+if line.Instrumented && !line.IsCovered {
+uncovered = append(uncovered, line.LineNumber)
+}
+}
+row.uncovered = FormatLineRanges(uncovered)
+}
+fileRows = append(fileRows, row)
+}
+if t.SortBy == "coverage" {
+for _, rows := range [][]textRow{packageRows, fileRows} {
+sort.SliceStable(rows, func(i, j int) bool {
+return rows[i].pct < rows[j].pct
+})
+}
+}
+totalStmts, coveredStmts, overallPct := t.Report.GetOverallStats()
+width := utf8.RuneCountInString("PACKAGE")
+for _, row := range append(append([]textRow{}, packageRows...), fileRows...) {
+width = max(width, utf8.RuneCountInString(row.name))
+}
+bw := bufio.NewWriter(w)
+t.writeHeader(bw, width, "PACKAGE", false)
+for _, row := range packageRows {
+t.writeRow(bw, width, row, false)
+}
+fmt.Fprintln(bw)
+t.writeHeader(bw, width, "FILE", t.ShowUncovered)
+for _, row := range fileRows {
+t.writeRow(bw, width, row, t.ShowUncovered)
+}
+fmt.Fprintln(bw)
+t.writeRow(bw, width, textRow{name: "TOTAL", pct: overallPct, total: totalStmts, covered: coveredStmts}, false)
+return bw.Flush()
+}
+func (t *TextReport) writeHeader(w io.Writer, width int, name string, uncovered bool) {
+line := fmt.Sprintf("%s  %8s  %12s", padRight(name, width), "COVERAGE", "STATEMENTS")
+if uncovered {
+line += "  UNCOVERED LINES"
+}
+fmt.Fprintln(w, t.ansi("1", line))
+}
+func (t *TextReport) writeRow(w io.Writer, width int, row textRow, uncovered bool) {
+pct := t.ansi(ansiCoverageColor(row.pct), fmt.Sprintf("%8s", FormatPercentage(row.pct)))
+line := fmt.Sprintf("%s  %s  %12s", padRight(row.name, width), pct, fmt.Sprintf("%d/%d", row.covered, row.total))
+if uncovered && row.uncovered != "" {
+line += "  " + row.uncovered
+}
+fmt.Fprintln(w, strings.TrimRight(line, " "))
+}
+func (t *TextReport) ansi(code, s string) string {
+if !t.Color {
+return s
+}
+return "\x1b[" + code + "m" + s + "\x1b[0m"
+}
+func ansiCoverageColor(pct float64) string {
+switch {
+case pct >= 80:
+return "32"
+case pct >= 60:
+return "33"
+default:
+return "31"
+}
+}
+func padRight(s string, width int) string {
+if n := utf8.RuneCountInString(s); n < width {
+return s + strings.Repeat(" ", width-n)
+}
+return s
+}
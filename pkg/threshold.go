@@ -0,0 +1,103 @@
+package coverage
+import (
+"fmt"
+"path"
+"sort"
+"strconv"
+"strings"
+)
+type Thresholds struct {
+MinTotal   float64
+MinFile    float64
+MinPackage float64
+Overrides  []ThresholdOverride
+}
+type ThresholdOverride struct {
+Pattern string
+Min     float64
+}
+type ThresholdViolation struct {
+Scope    string
+Path     string
+Coverage float64
+Minimum  float64
+Empty    bool
+}
+func (v ThresholdViolation) String() string {
+if v.Scope == "total" && v.Empty {
+return fmt.Sprintf("coverage report has no statements, cannot reach minimum %s", FormatPercentage(v.Minimum))
+}
+if v.Scope == "total" {
+return fmt.Sprintf("total coverage %s is below minimum %s", FormatPercentage(v.Coverage), FormatPercentage(v.Minimum))
+}
+return fmt.Sprintf("%s %s coverage %s is below minimum %s", v.Scope, v.Path, FormatPercentage(v.Coverage), FormatPercentage(v.Minimum))
+}
+func ParseThresholdOverride(value string) (ThresholdOverride, error) {
+i := strings.LastIndex(value, "=")
+if i <= 0 {
+return ThresholdOverride{}, fmt.Errorf("invalid override %q, expected <pattern>=<percent>", value)
+}
+min, err := strconv.ParseFloat(strings.TrimSuffix(value[i+1:], "%"), 64)
+if err != nil {
+return ThresholdOverride{}, fmt.Errorf("invalid override %q: %w", value, err)
+}
+return ThresholdOverride{Pattern: value[:i], Min: min}, nil
+}
+func (o ThresholdOverride) Matches(p string) bool {
+if strings.HasSuffix(o.Pattern, "/...") {
+prefix := strings.TrimSuffix(o.Pattern, "/...")
+return p == prefix || strings.HasPrefix(p, prefix+"/")
+}
+if matched, err := path.Match(o.Pattern, p); err == nil && matched {
+return true
+}
+return o.Pattern == p
+}
+func (t Thresholds) minimumFor(p string, fallback float64) float64 {
+for _, override := range t.Overrides {
+if override.Matches(p) {
+return override.Min
+}
+}
+return fallback
+}
+func CheckThresholds(report *CoverageReport, thresholds Thresholds) []ThresholdViolation {
+violations := []ThresholdViolation{}
+totalStmts, _, overallPct := report.GetOverallStats()
+if thresholds.MinTotal > 0 && (totalStmts == 0 || overallPct < thresholds.MinTotal) {
+violations = append(violations, ThresholdViolation{
+Scope:    "total",
+Coverage: overallPct,
+Minimum:  thresholds.MinTotal,
+Empty:    totalStmts == 0,
+})
+}
+for _, pkg := range report.Packages() {
+total, _, pct := pkg.GetCoverageStats()
+if min := thresholds.minimumFor(pkg.Path, thresholds.MinPackage); total > 0 && pct < min {
+violations = append(violations, ThresholdViolation{
+Scope:    "package",
+Path:     pkg.Path,
+Coverage: pct,
+Minimum:  min,
+})
+}
+}
+paths := make([]string, 0, len(report.Files))
+for p := range report.Files {
+paths = append(paths, p)
+}
+sort.Strings(paths)
+for _, p := range paths {
+total, _, pct := report.Files[p].GetCoverageStats()
+if min := thresholds.minimumFor(p, thresholds.MinFile); total > 0 && pct < min {
+violations = append(violations, ThresholdViolation{
+Scope:    "file",
+Path:     p,
+Coverage: pct,
+Minimum:  min,
+})
+}
+}
+return violations
+}
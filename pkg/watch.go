@@ -0,0 +1,82 @@
+package coverage
+import (
+"os"
+"path/filepath"
+"sort"
+"strings"
+)
+func ScanGoFiles(root string) (map[string]FileStamp, error) {
+stamps := make(map[string]FileStamp)
+err := filepath.WalkDir(root, func(path string, d os.DirEntry, err error) error {
+if err != nil {
+return err
+}
+name := d.Name()
+if d.IsDir() {
+if path == root {
+return nil
+}
+if name == "vendor" || name == "testdata" || strings.HasPrefix(name, ".") || strings.HasPrefix(name, "_") {
+return filepath.SkipDir
+}
+if _, err := os.Stat(filepath.Join(path, "go.mod")); err == nil {
+return filepath.SkipDir
+}
+return nil
+}
+if strings.HasSuffix(name, ".go") {
+stamps[path] = StatFile(path)
+}
+return nil
+})
+return stamps, err
+}
+func ChangedPackages(root string, before, after map[string]FileStamp) (packages, removed []string) {
+dirs := make(map[string]bool)
+for path, stamp := range after {
+if prev, ok := before[path]; !ok || prev != stamp {
+dirs[filepath.Dir(path)] = true
+}
+}
+for path := range before {
+if _, ok := after[path]; !ok {
+dirs[filepath.Dir(path)] = true
+}
+}
+for dir := range dirs {
+rel, err := filepath.Rel(root, dir)
+if err != nil {
+continue
+}
+pkg := "./" + filepath.ToSlash(rel)
+if rel == "." {
+pkg = "."
+}
+if hasGoFiles(after, dir) {
+packages = append(packages, pkg)
+} else {
+removed = append(removed, pkg)
+}
+}
+sort.Strings(packages)
+sort.Strings(removed)
+return packages, removed
+}
+func PackageImportPath(modulePath, pkg string) string {
+rel := strings.TrimPrefix(strings.TrimPrefix(pkg, "."), "/")
+switch {
+case rel == "":
+return modulePath
+case modulePath == "":
+return rel
+}
+return modulePath + "/" + rel
+}
+func hasGoFiles(stamps map[string]FileStamp, dir string) bool {
+for path := range stamps {
+if filepath.Dir(path) == dir {
+return true
+}
+}
+return false
+}